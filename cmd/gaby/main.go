@@ -9,9 +9,7 @@ import (
 	"strings"
 
 	"github.com/umdis/gaby-interpreter/internal/evaluator"
-	"github.com/umdis/gaby-interpreter/internal/lexer"
 	"github.com/umdis/gaby-interpreter/internal/object"
-	"github.com/umdis/gaby-interpreter/internal/parser"
 	"github.com/umdis/gaby-interpreter/stdlib"
 )
 
@@ -24,11 +22,13 @@ func main() {
 		panic(err)
 	}
 
-	// Inicializar el entorno global
-	env := object.NewEnvironment()
+	// Cargar las funciones de la biblioteca estándar en un entorno base que
+	// comparten el programa y los módulos que importe
+	base := object.NewEnvironment()
+	stdlib.LoadStdlib(base)
 	
-	// Cargar las funciones de la biblioteca estándar
-	stdlib.LoadStdlib(env)
+	// Inicializar el entorno global del programa
+	env := object.NewEnclosedEnvironment(base)
 
 	// Verificar argumentos
 	args := os.Args[1:]
@@ -125,16 +125,13 @@ func executeFile(filename string, env *object.Environment) {
 
 // evaluateInput evalúa una cadena de entrada y devuelve el resultado
 func evaluateInput(input string, env *object.Environment) object.Object {
-	l := lexer.New(input)
-	p := parser.New(l)
-	
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		printParserErrors(p.Errors())
+	evaluated, errors := evaluator.EvalSource(input, env)
+	if errors != nil {
+		printParserErrors(errors)
 		return nil
 	}
 	
-	return evaluator.Eval(program, env)
+	return evaluated
 }
 
 // printParserErrors imprime errores del parser
@@ -158,6 +155,40 @@ Ejemplos básicos:
   resultado := suma(5, 3)
   mostrar(resultado)
 
+  // Operadores numéricos
+  7 / 2        // 3.5: la división es verdadera y siempre da DECIMAL (6 / 3 es 2.0)
+  7 div 2      // 3: división entera, redondeada hacia abajo
+  6 & 3, 6 | 3, 6 ~ 3, ~6, 1 << 4, 16 >> 2  // y, o, o exclusivo, negación y desplazamientos de bits
+
+  // Navegación segura ante nulo
+  ciudad := usuario?.direccion?.ciudad ?? "desconocida"
+
+  // Índices y porciones
+  lista := [1, 2, 3, 4, 5]
+  lista[-1], lista[1:3], lista[::2], lista[::-1], "hola"[1:]
+  indices_estrictos(verdad)  // un índice fuera de rango es un error en lugar de nulo
+
+  // Conjuntos
+  a := {1, 2, 3}
+  b := conjunto([3, 4])
+  a | b, a & b, a - b, a ~ b  // unión, intersección, diferencia y diferencia simétrica
+  2 en a, {1, 2} <= a         // pertenencia y subconjunto
+  lista(a)
+
+  // Tuplas y claves compuestas
+  punto := (3, 4)
+  distancias := {punto: 5, 1.5: "decimal"}
+  // una clase con hash() e igual(otro) puede usarse como clave de mapas y conjuntos
+
+  // Igualdad e identidad
+  [1, [2, 3]] == [1, [2, 3]]  // verdad: listas, mapas e instancias se comparan por contenido
+  lista es otra_lista         // verdad solo si son el mismo objeto (también: no_es)
+
+  // Textos
+  "ana" < "beto", "ja" * 3
+  ordenar(["oso", "Ñandú", "nube"], "es")  // [nube, Ñandú, oso]: ñ tras la n, sin distinguir acentos
+  comparar("canción", "CANCION", "es")     // 0
+
   // Condicionales
   edad := 25
   si edad >= 18 {
@@ -165,12 +196,26 @@ Ejemplos básicos:
   } sino {
     mostrar("Menor de edad")
   }
+  etapa := "adulto" si edad >= 18 sino "menor"
+  precio := si edad < 12 { 5 } sino si edad >= 65 { 7 } sino { 10 }
 
   // Bucles
   para i desde 1 hasta 5 {
     mostrar(i)
   }
 
+  para nombre en ["Ana", "Luis"] {
+    mostrar(nombre)
+  }
+  para (clave, valor) en {"a": 1, "b": 2} {
+    mostrar(clave, valor)
+  }
+
+  // Iteración: una clase con iterador(), o con siguiente() y tiene_siguiente(),
+  // puede recorrerse con 'para', expandirse con ... y desestructurarse
+  todos := [0, ...otra_lista, 9]
+  guarda (primero, ...resto) = todos
+
   contador := 0
   mientras contador < 5 {
     mostrar(contador)
@@ -183,6 +228,35 @@ Ejemplos básicos:
   }
   mostrar(saludar("Gaby"))
 
+  // Generadores: 'producir' entrega un valor y suspende la función
+  fun pares(n) {
+    guarda i = 0
+    mientras (i < n) {
+      producir i * 2
+      i = i + 1
+    }
+  }
+  para p en pares(3) { mostrar(p) }
+
+  // Rangos: los números se generan al recorrerlos
+  para i en 1..=5 { mostrar(i) }      // 1..5 excluye el 5
+  guarda r = rango(0, 1000000, 2)      // fin incluido, paso opcional
+  mostrar(longitud(r), r[-1], 500 en r)
+  mostrar(lista(r[:3]))                // [0, 2, 4]
+  // rango() devuelve un RANGO y ya no una LISTA: agregar y eliminar lo
+  // aceptan, pero r[0] = x y r == [0, 2] necesitan lista(r)
+
+  // Comprensiones de listas, conjuntos y mapas
+  guarda nums = [3, -1, 4]
+  mostrar([x * 2 para x en nums si x > 0])           // [6, 8]
+  mostrar({x * x para x en nums})                    // {9, 1, 16}
+  mostrar({k: v + 1 para k, v en {"a": 1, "b": 2}})
+  mostrar([(i, j) para i en 1..3 para j en 1..3 si i != j])
+
+  // Tubería: el valor de la izquierda es el primer argumento, o va en '_'
+  "  hola  " |> recortar |> mayusculas |> mostrar
+  10 |> rango(1, _, 3) |> lista |> mostrar  // [1, 4, 7, 10]
+
   // Clases
   clase Persona {
     texto nombre
@@ -200,6 +274,96 @@ Ejemplos básicos:
   
   p := nuevo Persona("Juan", 30)
   p.presentarse()
+
+  // Campos y constructores: cada instancia evalúa sus propios campos;
+  // crear admite sobrecargas por cantidad de argumentos
+  clase Carrito {
+    guarda items = {}                 // no se comparte entre instancias
+    crear() { }
+    crear(dueno) {
+      esto.crear()                    // delega en la otra sobrecarga
+      esto.dueno = dueno
+    }
+  }
+  nuevo Carrito("Ana", 1)             // error: no hay crear con 2 argumentos
+
+  // Operadores propios: sumar_op, restar_op, multiplicar_op, dividir_op,
+  // modulo_op, potencia_op, negar_op, comparar (< > <= >=), igual (==),
+  // indice (obj[i]), asignar_indice (obj[i] = v) y a_texto (al mostrar)
+  clase Dinero {
+    fun crear(centavos) { esto.centavos = centavos }
+    fun sumar_op(otro) { devolver nuevo Dinero(esto.centavos + otro.centavos) }
+    fun comparar(otro) { devolver esto.centavos - otro.centavos }
+    fun a_texto() { devolver "$" + texto(esto.centavos / 100) }
+  }
+  mostrar(nuevo Dinero(150) + nuevo Dinero(250))   // $4
+
+  // Propiedades calculadas: se leen y asignan como un campo más
+  clase Circulo extiende Figura {
+    obtener area() { devolver 3.14159 * esto._radio * esto._radio }
+    obtener radio() { devolver esto._radio }
+    asignar radio(r) { esto._radio = abs(r) }    // sin 'asignar', solo lectura
+  }
+  c := nuevo Circulo()
+  c.radio = -2
+  mostrar(c.radio, c.area)
+
+  // Enumeraciones: valores únicos, ordenados y utilizables como claves
+  enum Semaforo { Rojo, Amarillo, Verde }
+  para s en Semaforo { mostrar(s.nombre, s.valor) }
+  guarda luz = Semaforo.Rojo
+  evaluar luz {
+    cuando Semaforo.Rojo { mostrar("pare") }
+    cuando Semaforo.Amarillo, Semaforo.Verde { mostrar("siga") }
+  }                            // sin 'defecto' advierte si falta algún miembro
+
+  // Clases abstractas: no se instancian y obligan a implementar sus métodos
+  abstracta clase Forma {
+    abstracta fun area()
+    fun describir() { devolver "área " + texto(esto.area()) }
+  }
+  clase Cuadrado extiende Forma {
+    fun crear(lado) { esto.lado = lado }
+    fun area() { devolver esto.lado * esto.lado }   // sin él, error al definir
+  }
+  mostrar(nuevo Cuadrado(3).describir())
+
+  // Rasgos: métodos compartidos que una clase incluye con 'usa'.
+  // Prioridad: la clase, luego los rasgos, luego el padre; dos rasgos con
+  // el mismo método obligan a definirlo en la clase
+  rasgo Ordenable {
+    abstracta fun comparar(otro)              // requerido
+    fun menor(otro) { devolver esto.comparar(otro) < 0 }
+  }
+  clase Nota usa Ordenable {
+    fun crear(valor) { esto.valor = valor }
+    fun comparar(otro) { devolver esto.valor - otro.valor }
+  }
+  mostrar(nuevo Nota(7).menor(nuevo Nota(9)))  // verdad
+
+  // Reflexión: los nombres que empiezan con '_' son privados
+  guarda nota = nuevo Nota(8)
+  mostrar(tipo(nota), clase_de(nota), campos(nota), metodos(Nota))
+  mostrar(tiene_metodo(nota, "menor"), obtener(nota, "valor"))
+  establecer(nota, "valor", 10)
+  mostrar(llamar(nota, "comparar", [nuevo Nota(4)]))   // 6
+
+  // Código dinámico en entornos aislados
+  mostrar(ejecutar_codigo("1 + 2 * 3")["valor"])   // 7, en un entorno nuevo
+  guarda r = ejecutar_codigo("1 +* 2")          // los errores no detienen el programa
+  mostrar(r["errores"][0]["linea"], r["errores"][0]["mensaje"])
+  guarda plugin = entorno({"limite": 10})       // entorno(), entorno(mapa) o entorno(otro)
+  ejecutar_codigo("guarda doble = limite * 2", plugin)
+  mostrar(plugin.doble, campos(plugin))         // 20, [doble, limite]
+  ejecutar_codigo("limite = 99", entorno(plugin))  // entorno(otro) copia sus variables
+  mostrar(plugin.limite)                        // 10: el original no cambia
+  // salir y cargar no existen en estos entornos; importar y leer sí
+
+  // Módulos: rutas relativas al archivo que importa; cada uno se evalúa una vez
+  importar "util/texto.gaby" como t             // sin 'como' se llama texto
+  desde "util/texto.gaby" importar saludo, gritar
+  mostrar(t.saludo, gritar("hola"))             // solo nombres sin '_' o con 'exportar'
+  // en util/texto.gaby: exportar fun gritar(s) { devolver mayusculas(s) }
 `
 	io.WriteString(out, help)
 }
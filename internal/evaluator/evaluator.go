@@ -1,11 +1,43 @@
 package evaluator
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/umdis/gaby-interpreter/internal/lexer"
 	"github.com/umdis/gaby-interpreter/internal/object"
 	"github.com/umdis/gaby-interpreter/internal/parser"
 )
 
+func init() {
+	object.InstanceText = instanceText
+}
+
+// instanceText obtiene el texto de una instancia con a_texto(); si la clase
+// no lo define o no devuelve un TEXTO se usa la representación genérica
+func instanceText(instance *object.Instance) (string, bool) {
+	if findMethod(instance.Class, "a_texto") == nil {
+		return "", false
+	}
+	result, ok := callMethod(instance, "a_texto").(*object.String)
+	if !ok {
+		return "", false
+	}
+	return result.Value, true
+}
+
+// pipePlaceholder marca dónde va el valor en 'x |> f(a, _)'
+const pipePlaceholder = "_"
+
 // Objetos singleton para optimizar la creación de objetos comunes
 var (
 	TRUE  = &object.Boolean{Value: true}
@@ -28,6 +60,12 @@ func Eval(node parser.Node, env *object.Environment) object.Object {
 		if isError(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			if err := destructure(node.Pattern, val, env); err != nil {
+				return err
+			}
+			return val
+		}
 		env.Set(node.Name.Value, val)
 		return val
 	case *parser.ReturnStatement:
@@ -36,12 +74,22 @@ func Eval(node parser.Node, env *object.Environment) object.Object {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *parser.ImportStatement:
+		return evalImportStatement(node, env)
+	case *parser.ExportStatement:
+		return Eval(node.Statement, env)
 
 	// Expresiones
 	case *parser.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 	case *parser.FloatLiteral:
 		return &object.Float{Value: node.Value}
+	case *parser.DecimalLiteral:
+		value, err := object.ParseDecimal(node.Value)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return value
 	case *parser.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *parser.BooleanLiteral:
@@ -59,6 +107,16 @@ func Eval(node parser.Node, env *object.Environment) object.Object {
 		if isError(left) {
 			return left
 		}
+		// '??' solo evalúa el lado derecho si el izquierdo es nulo
+		if node.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
+		if node.Operator == "|>" {
+			return evalPipeExpression(left, node.Right, env)
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
@@ -66,31 +124,56 @@ func Eval(node parser.Node, env *object.Environment) object.Object {
 		return evalInfixExpression(node.Operator, left, right)
 	case *parser.IfExpression:
 		return evalIfExpression(node, env)
+	case *parser.ConditionalExpression:
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(node.Consequence, env)
+		}
+		return Eval(node.Alternative, env)
 	case *parser.WhileExpression:
 		return evalWhileExpression(node, env)
 	case *parser.ForExpression:
 		return evalForExpression(node, env)
+	case *parser.ForInExpression:
+		return evalForInExpression(node, env)
+	case *parser.SpreadExpression:
+		return newError("'...' solo puede usarse dentro de listas, tuplas, conjuntos o argumentos")
 	case *parser.Identifier:
 		return evalIdentifier(node, env)
 	case *parser.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{
-			Parameters: params,
-			Body:       body,
-			Env:        env,
-			Name:       node.Name,
+		fn := &object.Function{
+			Parameters:  params,
+			Body:        body,
+			Env:         env,
+			Name:        node.Name,
+			IsGenerator: node.IsGenerator,
 		}
-	case *parser.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
+		// fun nombre() { ... } también declara la función con ese nombre
+		if node.Name != "" {
+			env.Set(node.Name, fn)
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		return fn
+	case *parser.YieldExpression:
+		var value object.Object = NULL
+		if node.Value != nil {
+			value = Eval(node.Value, env)
+			if isError(value) {
+				return value
+			}
+		}
+		yield, ok := env.Get(yieldBinding)
+		if !ok {
+			return newError("producir solo puede usarse dentro de una función generadora")
 		}
-		return applyFunction(function, args)
+		return applyFunction(yield, []object.Object{value})
+	case *parser.CallExpression:
+		result, _ := evalChain(node, env)
+		return result
 	case *parser.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -98,25 +181,40 @@ func Eval(node parser.Node, env *object.Environment) object.Object {
 		}
 		return &object.Array{Elements: elements}
 	case *parser.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
-		}
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
-		}
-		return evalIndexExpression(left, index)
+		result, _ := evalChain(node, env)
+		return result
+	case *parser.SliceExpression:
+		result, _ := evalChain(node, env)
+		return result
 	case *parser.HashLiteral:
 		return evalHashLiteral(node, env)
-	case *parser.DotExpression:
-		obj := Eval(node.Object, env)
-		if isError(obj) {
-			return obj
+	case *parser.TupleLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Tuple{Elements: elements}
+	case *parser.SetLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
 		}
-		return evalDotExpression(obj, node.Property.Value)
+		return NewSet(elements)
+	case *parser.ComprehensionExpression:
+		return evalComprehension(node, env)
+	case *parser.DotExpression:
+		result, _ := evalChain(node, env)
+		return result
+	case *parser.AssignExpression:
+		return evalAssignExpression(node, env)
 	case *parser.ClassLiteral:
 		return evalClassLiteral(node, env)
+	case *parser.TraitLiteral:
+		return evalTraitLiteral(node, env)
+	case *parser.EnumLiteral:
+		return evalEnumLiteral(node, env)
+	case *parser.SwitchExpression:
+		return evalSwitchExpression(node, env)
 	case *parser.NewExpression:
 		return evalNewExpression(node, env)
 	}
@@ -141,8 +239,10 @@ func evalProgram(program *parser.Program, env *object.Environment) object.Object
 	return result
 }
 
+// evalBlockStatement devuelve el valor de la última sentencia del bloque, o
+// nulo si está vacío; así un bloque 'si' puede usarse como valor
 func evalBlockStatement(block *parser.BlockStatement, env *object.Environment) object.Object {
-	var result object.Object
+	var result object.Object = NULL
 
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
@@ -161,6 +261,8 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalBitwiseNotOperatorExpression(right)
 	default:
 		return newError("operador de prefijo desconocido: %s%s", operator, right.Type())
 	}
@@ -187,13 +289,42 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	case object.FLOAT_OBJ:
 		value := right.(*object.Float).Value
 		return &object.Float{Value: -value}
+	case object.DECIMAL_OBJ:
+		return right.(*object.Decimal).Neg()
+	case object.RATIONAL_OBJ:
+		value := right.(*object.Rational).Value
+		return &object.Rational{Value: new(big.Rat).Neg(value)}
+	case object.INSTANCE_OBJ:
+		instance := right.(*object.Instance)
+		if findMethod(instance.Class, "negar_op") != nil {
+			return callMethod(instance, "negar_op")
+		}
+		return newError("operador de prefijo desconocido: -%s (defina negar_op())", instance.Class.Name)
 	default:
 		return newError("operador de prefijo desconocido: -%s", right.Type())
 	}
 }
 
+func evalBitwiseNotOperatorExpression(right object.Object) object.Object {
+	if right.Type() != object.INTEGER_OBJ {
+		return newError("operador de prefijo desconocido: ~%s", right.Type())
+	}
+	value := right.(*object.Integer).Value
+	return &object.Integer{Value: ^value}
+}
+
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
+	if result, ok := evalOperatorOverload(operator, left, right); ok {
+		return result
+	}
+
 	switch {
+	case operator == "en":
+		return evalMembershipExpression(left, right)
+	case operator == "es":
+		return nativeBoolToBooleanObject(isIdentical(left, right))
+	case operator == "no_es":
+		return nativeBoolToBooleanObject(!isIdentical(left, right))
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.FLOAT_OBJ && right.Type() == object.FLOAT_OBJ:
@@ -210,12 +341,81 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 		floatValue := float64(intValue)
 		rightAsFloat := &object.Float{Value: floatValue}
 		return evalFloatInfixExpression(operator, left, rightAsFloat)
+	case left.Type() == object.DECIMAL_OBJ && right.Type() == object.DECIMAL_OBJ:
+		return evalDecimalInfixExpression(operator, left, right)
+	case left.Type() == object.DECIMAL_OBJ && right.Type() == object.INTEGER_OBJ:
+		// Los enteros se convierten a decimal exacto sin pérdida
+		rightAsDecimal := object.NewDecimalFromInt(right.(*object.Integer).Value)
+		return evalDecimalInfixExpression(operator, left, rightAsDecimal)
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.DECIMAL_OBJ:
+		leftAsDecimal := object.NewDecimalFromInt(left.(*object.Integer).Value)
+		return evalDecimalInfixExpression(operator, leftAsDecimal, right)
+	case left.Type() == object.DECIMAL_OBJ && right.Type() == object.FLOAT_OBJ,
+		left.Type() == object.FLOAT_OBJ && right.Type() == object.DECIMAL_OBJ:
+		// Mezclar con DECIMAL perdería la exactitud; la conversión debe ser explícita
+		return newError("no se puede mezclar %s con %s en '%s': use decimal(x) para convertir",
+			left.Type(), right.Type(), operator)
+	case left.Type() == object.RATIONAL_OBJ || right.Type() == object.RATIONAL_OBJ:
+		return evalMixedRationalInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
-	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
-	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+	case operator == "*" && left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
+		return evalStringRepetition(left.(*object.String), right.(*object.Integer))
+	case operator == "*" && left.Type() == object.INTEGER_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringRepetition(right.(*object.String), left.(*object.Integer))
+	case left.Type() == object.SET_OBJ && right.Type() == object.SET_OBJ:
+		return evalSetInfixExpression(operator, left.(*object.Set), right.(*object.Set))
+	case left.Type() == object.ENUM_MEMBER_OBJ && right.Type() == object.ENUM_MEMBER_OBJ:
+		return evalEnumInfixExpression(operator, left.(*object.EnumMember), right.(*object.EnumMember))
+	default:
+		return evalNonNumericInfixExpression(operator, left, right)
+	}
+}
+
+// isIdentical resuelve 'es'. Los números, textos, booleanos y nulo no tienen
+// identidad propia, así que son idénticos si tienen el mismo tipo y valor; el
+// resto solo si son el mismo objeto.
+func isIdentical(left, right object.Object) bool {
+	if left == right {
+		return true
+	}
+
+	switch left := left.(type) {
+	case *object.Integer:
+		other, ok := right.(*object.Integer)
+		return ok && left.Value == other.Value
+	case *object.Float:
+		other, ok := right.(*object.Float)
+		return ok && left.Value == other.Value
+	case *object.Decimal:
+		other, ok := right.(*object.Decimal)
+		return ok && left.Cmp(other) == 0
+	case *object.Rational:
+		other, ok := right.(*object.Rational)
+		return ok && left.Value.Cmp(other.Value) == 0
+	case *object.String:
+		other, ok := right.(*object.String)
+		return ok && left.Value == other.Value
+	case *object.Boolean:
+		other, ok := right.(*object.Boolean)
+		return ok && left.Value == other.Value
+	case *object.Null:
+		_, ok := right.(*object.Null)
+		return ok
+	default:
+		return false
+	}
+}
+
+// evalNonNumericInfixExpression resuelve los operadores que no dependen del tipo de los operandos
+func evalNonNumericInfixExpression(operator string, left, right object.Object) object.Object {
+	switch {
+	case operator == "==" || operator == "!=":
+		equal, err := objectsEqual(left, right, map[visitedPair]bool{})
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(equal == (operator == "=="))
 	case operator == "y":
 		return evalLogicalAndOperator(left, right)
 	case operator == "o":
@@ -227,6 +427,113 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 }
 
+// operatorMethods asocia cada operador aritmético con el método que una
+// clase puede definir para darle sentido: v1 + v2 llama a v1.sumar_op(v2)
+var operatorMethods = map[string]string{
+	"+": "sumar_op",
+	"-": "restar_op",
+	"*": "multiplicar_op",
+	"/": "dividir_op",
+	"%": "modulo_op",
+	"^": "potencia_op",
+}
+
+// evalOperatorOverload resuelve los operadores sobre instancias que definen
+// el método correspondiente. Los aritméticos usan el método del operando
+// izquierdo; < > <= >= usan comparar(otro) de cualquiera de los dos. '==' y
+// '!=' se resuelven con igual(otro) en objectsEqual. Indica con el segundo
+// valor si el operador se resolvió.
+func evalOperatorOverload(operator string, left, right object.Object) (object.Object, bool) {
+	leftInstance, leftOk := left.(*object.Instance)
+	_, rightOk := right.(*object.Instance)
+	if !leftOk && !rightOk {
+		return nil, false
+	}
+
+	if name, ok := operatorMethods[operator]; ok {
+		if !leftOk {
+			return nil, false
+		}
+		if findMethod(leftInstance.Class, name) == nil {
+			return newError("la clase %s no define %s(otro) para '%s'", leftInstance.Class.Name, name, operator), true
+		}
+		return callMethod(leftInstance, name, right), true
+	}
+
+	switch operator {
+	case "<", ">", "<=", ">=":
+		order, err := compareInstances(left, right)
+		if err != nil {
+			return err, true
+		}
+		switch operator {
+		case "<":
+			return nativeBoolToBooleanObject(order < 0), true
+		case ">":
+			return nativeBoolToBooleanObject(order > 0), true
+		case "<=":
+			return nativeBoolToBooleanObject(order <= 0), true
+		default:
+			return nativeBoolToBooleanObject(order >= 0), true
+		}
+	}
+
+	return nil, false
+}
+
+// compareInstances ordena dos valores cuando al menos uno es una instancia
+// con comparar(otro), que debe devolver un número negativo, cero o positivo
+func compareInstances(left, right object.Object) (int, *object.Error) {
+	instance, ok := left.(*object.Instance)
+	sign := 1
+	if !ok || findMethod(instance.Class, "comparar") == nil {
+		// Se pregunta al operando derecho y se invierte el resultado
+		instance, ok = right.(*object.Instance)
+		if !ok || findMethod(instance.Class, "comparar") == nil {
+			return 0, newError("no se pueden comparar %s y %s: defina comparar(otro)", left.Type(), right.Type())
+		}
+		right, sign = left, -1
+	}
+
+	result := callMethod(instance, "comparar", right)
+	if err, ok := result.(*object.Error); ok {
+		return 0, err
+	}
+
+	order, err := Compare(result, &object.Integer{Value: 0}, CollationBinary)
+	if err != nil {
+		return 0, newError("comparar() de %s debe devolver un número, se obtuvo %s", instance.Class.Name, result.Type())
+	}
+	return order * sign, nil
+}
+
+// evalEnumInfixExpression compara dos valores de enumeración: son iguales
+// solo si son el mismo valor y se ordenan por su posición en la declaración
+func evalEnumInfixExpression(operator string, left, right *object.EnumMember) object.Object {
+	switch operator {
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	}
+
+	if left.Enum != right.Enum {
+		return newError("no se pueden comparar valores de enums distintos: %s %s %s", left.Inspect(), operator, right.Inspect())
+	}
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(left.Ordinal < right.Ordinal)
+	case ">":
+		return nativeBoolToBooleanObject(left.Ordinal > right.Ordinal)
+	case "<=":
+		return nativeBoolToBooleanObject(left.Ordinal <= right.Ordinal)
+	case ">=":
+		return nativeBoolToBooleanObject(left.Ordinal >= right.Ordinal)
+	default:
+		return evalNonNumericInfixExpression(operator, left, right)
+	}
+}
+
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
@@ -239,15 +546,42 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		// División verdadera: el resultado siempre es DECIMAL, sea exacta o no
+		// (6 / 3 = 2.0, 7 / 2 = 3.5), así su tipo no depende de los valores.
+		// La única división entera es 'div'.
 		if rightVal == 0 {
 			return newError("división por cero")
 		}
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Float{Value: float64(leftVal) / float64(rightVal)}
+	case "div":
+		// División entera redondeada hacia abajo: 7 div 2 = 3, -7 div 2 = -4
+		if rightVal == 0 {
+			return newError("división por cero")
+		}
+		quotient := leftVal / rightVal
+		if leftVal%rightVal != 0 && (leftVal < 0) != (rightVal < 0) {
+			quotient--
+		}
+		return &object.Integer{Value: quotient}
 	case "%":
 		if rightVal == 0 {
 			return newError("módulo por cero")
 		}
 		return &object.Integer{Value: leftVal % rightVal}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "~":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("desplazamiento negativo: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << uint64(rightVal)}
+		}
+		return &object.Integer{Value: leftVal >> uint64(rightVal)}
 	case "^":
 		// Implementación simple de potencia para enteros
 		result := int64(1)
@@ -259,10 +593,18 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "..":
+		return &object.Range{Start: leftVal, Stop: rightVal, Step: 1}
+	case "..=":
+		return &object.Range{Start: leftVal, Stop: rightVal, Step: 1, Inclusive: true}
 	default:
 		return newError("operador desconocido: %s %s %s", left.Type(), operator, right.Type())
 	}
@@ -284,6 +626,11 @@ func evalFloatInfixExpression(operator string, left, right object.Object) object
 			return newError("división por cero")
 		}
 		return &object.Float{Value: leftVal / rightVal}
+	case "div":
+		if rightVal == 0 {
+			return newError("división por cero")
+		}
+		return &object.Float{Value: math.Floor(leftVal / rightVal)}
 	case "%":
 		if rightVal == 0 {
 			return newError("módulo por cero")
@@ -301,6 +648,10 @@ func evalFloatInfixExpression(operator string, left, right object.Object) object
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -310,6 +661,149 @@ func evalFloatInfixExpression(operator string, left, right object.Object) object
 	}
 }
 
+func evalDecimalInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.Decimal)
+	rightVal := right.(*object.Decimal)
+
+	switch operator {
+	case "+":
+		return leftVal.Add(rightVal)
+	case "-":
+		return leftVal.Sub(rightVal)
+	case "*":
+		return leftVal.Mul(rightVal)
+	case "/":
+		if rightVal.IsZero() {
+			return newError("división por cero")
+		}
+		// Se divide con la escala configurada y se eliminan los ceros sobrantes,
+		// sin bajar de la escala de los operandos (d"10.00" / 4 = 2.50)
+		minScale := max(leftVal.Scale, rightVal.Scale)
+		scale := max(object.DecimalDivisionScale, minScale)
+		return leftVal.Quo(rightVal, scale, object.DecimalRounding).TrimTo(minScale)
+	case "div":
+		if rightVal.IsZero() {
+			return newError("división por cero")
+		}
+		quotient := new(big.Rat).Quo(leftVal.Rat(), rightVal.Rat())
+		return &object.Decimal{Coef: floorRat(quotient), Scale: 0}
+	case "%":
+		if rightVal.IsZero() {
+			return newError("módulo por cero")
+		}
+		return leftVal.Rem(rightVal)
+	case "^":
+		exponent := rightVal.Normalize()
+		if exponent.Scale != 0 || exponent.Coef.Sign() < 0 || !exponent.Coef.IsInt64() {
+			return newError("el exponente de un %s debe ser un entero no negativo", object.DECIMAL_OBJ)
+		}
+		result := object.NewDecimalFromInt(1)
+		for i := int64(0); i < exponent.Coef.Int64(); i++ {
+			result = result.Mul(leftVal)
+		}
+		return result
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("operador desconocido: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalMixedRationalInfixExpression opera una fracción con otro número. Con
+// ENTERO y DECIMAL_EXACTO el resultado sigue siendo exacto; con DECIMAL la
+// fracción se convierte a decimal de coma flotante.
+func evalMixedRationalInfixExpression(operator string, left, right object.Object) object.Object {
+	if left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ {
+		leftAsFloat, okLeft := toFloat(left)
+		rightAsFloat, okRight := toFloat(right)
+		if okLeft && okRight {
+			return evalFloatInfixExpression(operator, leftAsFloat, rightAsFloat)
+		}
+	}
+
+	leftAsRational, okLeft := toRational(left)
+	rightAsRational, okRight := toRational(right)
+	if !okLeft || !okRight {
+		return evalNonNumericInfixExpression(operator, left, right)
+	}
+
+	return evalRationalInfixExpression(operator, leftAsRational, rightAsRational)
+}
+
+func evalRationalInfixExpression(operator string, left, right *object.Rational) object.Object {
+	leftVal := left.Value
+	rightVal := right.Value
+
+	switch operator {
+	case "+":
+		return &object.Rational{Value: new(big.Rat).Add(leftVal, rightVal)}
+	case "-":
+		return &object.Rational{Value: new(big.Rat).Sub(leftVal, rightVal)}
+	case "*":
+		return &object.Rational{Value: new(big.Rat).Mul(leftVal, rightVal)}
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError("división por cero")
+		}
+		return &object.Rational{Value: new(big.Rat).Quo(leftVal, rightVal)}
+	case "div":
+		if rightVal.Sign() == 0 {
+			return newError("división por cero")
+		}
+		quotient := new(big.Rat).Quo(leftVal, rightVal)
+		return &object.Rational{Value: new(big.Rat).SetInt(floorRat(quotient))}
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError("módulo por cero")
+		}
+		// a - b * trunc(a / b), con el signo del dividendo como en ENTERO
+		quotient := new(big.Rat).Quo(leftVal, rightVal)
+		truncated := new(big.Int).Quo(quotient.Num(), quotient.Denom())
+		product := new(big.Rat).Mul(rightVal, new(big.Rat).SetInt(truncated))
+		return &object.Rational{Value: product.Sub(leftVal, product)}
+	case "^":
+		if !rightVal.IsInt() || !rightVal.Num().IsInt64() {
+			return newError("el exponente de una %s debe ser un entero", object.RATIONAL_OBJ)
+		}
+		exponent := rightVal.Num().Int64()
+		if exponent < 0 && leftVal.Sign() == 0 {
+			return newError("división por cero")
+		}
+		base := new(big.Rat).Set(leftVal)
+		if exponent < 0 {
+			base.Inv(base)
+			exponent = -exponent
+		}
+		num := new(big.Int).Exp(base.Num(), big.NewInt(exponent), nil)
+		den := new(big.Int).Exp(base.Denom(), big.NewInt(exponent), nil)
+		return &object.Rational{Value: new(big.Rat).SetFrac(num, den)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError("operador desconocido: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
@@ -321,80 +815,662 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
+	// Orden lexicográfico por punto de código; para ordenar nombres en
+	// español se usa comparar(a, b, "es")
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	default:
 		return newError("operador desconocido: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalLogicalAndOperator(left, right object.Object) object.Object {
-	if !isTruthy(left) {
-		return left
-	}
-	return right
-}
+// maxStringLength limita en bytes el texto que puede crear una repetición
+const maxStringLength = 1 << 28
 
-func evalLogicalOrOperator(left, right object.Object) object.Object {
-	if isTruthy(left) {
-		return left
+// evalStringRepetition resuelve texto * n
+func evalStringRepetition(str *object.String, times *object.Integer) object.Object {
+	if times.Value < 0 {
+		return newError("no se puede repetir un texto un número negativo de veces: %d", times.Value)
 	}
-	return right
+	// Se comprueba dividiendo para que el producto no llegue a desbordarse
+	if len(str.Value) > 0 && times.Value > maxStringLength/int64(len(str.Value)) {
+		return newError("el texto repetido superaría el tamaño máximo de %d bytes", maxStringLength)
+	}
+	return &object.String{Value: strings.Repeat(str.Value, int(times.Value))}
 }
 
-func evalIfExpression(ie *parser.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
-	if isError(condition) {
-		return condition
+// Intercalaciones disponibles para comparar textos
+const (
+	CollationBinary  = "binario" // por punto de código, igual que '<'
+	CollationSpanish = "es"      // ñ tras la n, sin distinguir acentos ni mayúsculas
+)
+
+// Compare ordena dos números, dos textos, dos valores del mismo enum o
+// instancias con comparar(otro) y
+// devuelve -1, 0 o 1; los textos se comparan con la intercalación indicada
+func Compare(left, right object.Object, collation string) (int, *object.Error) {
+	if collation != CollationBinary && collation != CollationSpanish {
+		return 0, newError("intercalación desconocida: %q (use %q o %q)", collation, CollationBinary, CollationSpanish)
 	}
 
-	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
-	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
-	} else {
-		return NULL
+	if l, ok := left.(*object.String); ok {
+		if r, ok := right.(*object.String); ok {
+			if collation == CollationSpanish {
+				return compareRunes(spanishCollationKey(l.Value), spanishCollationKey(r.Value)), nil
+			}
+			return strings.Compare(l.Value, r.Value), nil
+		}
 	}
-}
 
-func evalWhileExpression(we *parser.WhileExpression, env *object.Environment) object.Object {
-	var result object.Object = NULL
+	if left.Type() == object.INSTANCE_OBJ || right.Type() == object.INSTANCE_OBJ {
+		return compareInstances(left, right)
+	}
 
-	for {
-		condition := Eval(we.Condition, env)
-		if isError(condition) {
-			return condition
+	if l, ok := left.(*object.EnumMember); ok {
+		if r, ok := right.(*object.EnumMember); ok && l.Enum == r.Enum {
+			return int(min(max(l.Ordinal-r.Ordinal, -1), 1)), nil
 		}
+	}
 
-		if !isTruthy(condition) {
-			break
+	if isNumeric(left) && isNumeric(right) {
+		for _, check := range []struct {
+			operator string
+			result   int
+		}{{"<", -1}, {">", 1}} {
+			result := evalInfixExpression(check.operator, left, right)
+			if err, ok := result.(*object.Error); ok {
+				return 0, err
+			}
+			if result == TRUE {
+				return check.result, nil
+			}
 		}
+		return 0, nil
+	}
 
-		result = Eval(we.Body, env)
-		if isError(result) {
-			return result
-		}
+	return 0, newError("no se pueden comparar %s y %s", left.Type(), right.Type())
+}
 
-		// Manejar sentencias de retorno, pero no salir del bucle por ellas
-		if result != nil && result.Type() == object.RETURN_VALUE_OBJ {
-			return result
+// spanishCollationKey convierte un texto en una clave cuyo orden por runas es
+// el alfabético español: sin mayúsculas ni acentos y con la ñ como letra
+// propia entre la n y la o. Cada runa se duplica para dejar hueco a la ñ.
+func spanishCollationKey(s string) []rune {
+	key := make([]rune, 0, len(s))
+	for _, r := range strings.ToLower(s) {
+		if r == 'ñ' {
+			key = append(key, 'n'*2+1)
+			continue
+		}
+		if base, ok := unaccented[r]; ok {
+			r = base
 		}
+		key = append(key, r*2)
 	}
+	return key
+}
 
-	return result
+var unaccented = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c',
 }
 
-func evalForExpression(fe *parser.ForExpression, env *object.Environment) object.Object {
-	// Crear un entorno separado para el bucle
-	loopEnv := object.NewEnclosedEnvironment(env)
+func compareRunes(a, b []rune) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	default:
+		return 0
+	}
+}
 
-	// Inicialización
-	if fe.Init != nil {
-		initResult := Eval(fe.Init, loopEnv)
-		if isError(initResult) {
-			return initResult
+// evalSetInfixExpression resuelve las operaciones de conjuntos: unión (|),
+// intersección (&), diferencia (-), diferencia simétrica (~) y las
+// comparaciones de subconjunto (<=, <) y superconjunto (>=, >)
+func evalSetInfixExpression(operator string, left, right *object.Set) object.Object {
+	switch operator {
+	case "|":
+		result := left.Copy()
+		for _, e := range right.Items() {
+			if err := SetAdd(result, e); err != nil {
+				return err
+			}
+		}
+		return result
+	case "&":
+		return filterSet(left, right, true)
+	case "-":
+		return filterSet(left, right, false)
+	case "~":
+		onlyLeft := filterSet(left, right, false)
+		if isError(onlyLeft) {
+			return onlyLeft
+		}
+		onlyRight := filterSet(right, left, false)
+		if isError(onlyRight) {
+			return onlyRight
 		}
+		return evalSetInfixExpression("|", onlyLeft.(*object.Set), onlyRight.(*object.Set))
 	}
 
-	var result object.Object = NULL
+	// Comparaciones: se reducen a preguntar si un lado es subconjunto del otro
+	sub, super := left, right
+	if operator == ">=" || operator == ">" {
+		sub, super = right, left
+	}
+
+	isSubset := sub.Len() <= super.Len()
+	for _, e := range sub.Items() {
+		if !isSubset {
+			break
+		}
+		found, err := setContains(super, e)
+		if err != nil {
+			return err
+		}
+		isSubset = found
+	}
+
+	switch operator {
+	case "==":
+		return nativeBoolToBooleanObject(isSubset && left.Len() == right.Len())
+	case "!=":
+		return nativeBoolToBooleanObject(!(isSubset && left.Len() == right.Len()))
+	case "<=", ">=":
+		return nativeBoolToBooleanObject(isSubset)
+	case "<", ">":
+		return nativeBoolToBooleanObject(isSubset && sub.Len() < super.Len())
+	default:
+		return newError("operador desconocido: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// filterSet devuelve los elementos de set que están (o no están, según
+// members) en other
+func filterSet(set, other *object.Set, members bool) object.Object {
+	result := object.NewSet()
+	for _, e := range set.Items() {
+		found, err := setContains(other, e)
+		if err != nil {
+			return err
+		}
+		if found == members {
+			if err := SetAdd(result, e); err != nil {
+				return err
+			}
+		}
+	}
+	return result
+}
+
+// visitedPair es un par de valores que se está comparando; permite que
+// objectsEqual termine con estructuras cíclicas
+type visitedPair struct {
+	left, right object.Object
+}
+
+// objectsEqual compara dos valores estructuralmente: listas, tuplas y mapas
+// por su contenido e instancias campo a campo, salvo que su clase defina
+// igual(otro). Un par que ya se está comparando se considera igual, así una
+// estructura cíclica es igual a otra con la misma forma.
+func objectsEqual(left, right object.Object, seen map[visitedPair]bool) (bool, *object.Error) {
+	if left == right {
+		return true, nil
+	}
+
+	pair := visitedPair{left, right}
+	if seen[pair] {
+		return true, nil
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		other, ok := right.(*object.Array)
+		if !ok {
+			return false, nil
+		}
+		seen[pair] = true
+		return sequencesEqual(left.Elements, other.Elements, seen)
+	case *object.Tuple:
+		other, ok := right.(*object.Tuple)
+		if !ok {
+			return false, nil
+		}
+		seen[pair] = true
+		return sequencesEqual(left.Elements, other.Elements, seen)
+	case *object.Hash:
+		other, ok := right.(*object.Hash)
+		if !ok || left.Len() != other.Len() {
+			return false, nil
+		}
+		seen[pair] = true
+		for _, leftPair := range left.Items() {
+			slot, found, err := hashSlot(other, leftPair.Key)
+			if err != nil || !found {
+				return false, err
+			}
+			rightPair, _ := other.Get(slot)
+			equal, err := objectsEqual(leftPair.Value, rightPair.Value, seen)
+			if err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	case *object.Set:
+		other, ok := right.(*object.Set)
+		if !ok {
+			return false, nil
+		}
+		result := evalSetInfixExpression("==", left, other)
+		if err, ok := result.(*object.Error); ok {
+			return false, err
+		}
+		return result == TRUE, nil
+	case *object.Range:
+		// Dos rangos son iguales si producen los mismos números
+		other, ok := right.(*object.Range)
+		if !ok {
+			return false, nil
+		}
+		leftLast, leftOk := left.Last()
+		otherLast, otherOk := other.Last()
+		if !leftOk || !otherOk {
+			return leftOk == otherOk, nil
+		}
+		return left.Start == other.Start && leftLast == otherLast &&
+			(left.Start == leftLast || left.Step == other.Step), nil
+	case *object.Instance:
+		other, ok := right.(*object.Instance)
+		if !ok {
+			return false, nil
+		}
+		if findMethod(left.Class, "igual") != nil {
+			result := callMethod(left, "igual", other)
+			if err, ok := result.(*object.Error); ok {
+				return false, err
+			}
+			return isTruthy(result), nil
+		}
+		if left.Class != other.Class || len(left.Properties) != len(other.Properties) {
+			return false, nil
+		}
+		seen[pair] = true
+		for name, value := range left.Properties {
+			otherValue, ok := other.Properties[name]
+			if !ok {
+				return false, nil
+			}
+			equal, err := objectsEqual(value, otherValue, seen)
+			if err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	switch right.(type) {
+	case *object.Array, *object.Tuple, *object.Hash, *object.Set, *object.Range, *object.Instance:
+		return false, nil
+	}
+
+	// Valores simples: los números se comparan con su propio '==' aunque sean
+	// de tipos distintos; el resto (funciones, clases, nulo, booleanos) por identidad
+	if isNumeric(left) && isNumeric(right) {
+		result := evalInfixExpression("==", left, right)
+		if err, ok := result.(*object.Error); ok {
+			return false, err
+		}
+		return result == TRUE, nil
+	}
+	if left, ok := left.(*object.String); ok {
+		other, ok := right.(*object.String)
+		return ok && left.Value == other.Value, nil
+	}
+	return false, nil
+}
+
+func isNumeric(obj object.Object) bool {
+	switch obj.Type() {
+	case object.INTEGER_OBJ, object.FLOAT_OBJ, object.DECIMAL_OBJ, object.RATIONAL_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
+func sequencesEqual(left, right []object.Object, seen map[visitedPair]bool) (bool, *object.Error) {
+	if len(left) != len(right) {
+		return false, nil
+	}
+	for i := range left {
+		equal, err := objectsEqual(left[i], right[i], seen)
+		if err != nil || !equal {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// evalMembershipExpression resuelve 'elemento en coleccion'
+func evalMembershipExpression(element, collection object.Object) object.Object {
+	switch collection := collection.(type) {
+	case *object.Set:
+		found, err := setContains(collection, element)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(found)
+	case *object.Hash:
+		_, found, err := hashSlot(collection, element)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(found)
+	case *object.Array:
+		return evalSequenceMembership(element, collection.Elements)
+	case *object.Tuple:
+		return evalSequenceMembership(element, collection.Elements)
+	case *object.Enum:
+		member, ok := element.(*object.EnumMember)
+		return nativeBoolToBooleanObject(ok && member.Enum == collection)
+	case *object.Range:
+		// Se calcula sin recorrer el rango; 2.0 cuenta como 2
+		switch element := element.(type) {
+		case *object.Integer:
+			return nativeBoolToBooleanObject(collection.Contains(element.Value))
+		case *object.Float:
+			value := int64(element.Value)
+			return nativeBoolToBooleanObject(float64(value) == element.Value && collection.Contains(value))
+		}
+		return FALSE
+	case *object.String:
+		sub, ok := element.(*object.String)
+		if !ok {
+			return newError("'en' sobre un TEXTO requiere un TEXTO, se obtuvo %s", element.Type())
+		}
+		return nativeBoolToBooleanObject(strings.Contains(collection.Value, sub.Value))
+	default:
+		return newError("operador 'en' no soportado para: %s", collection.Type())
+	}
+}
+
+// evalSequenceMembership busca un elemento igual (==) en una lista o tupla
+func evalSequenceMembership(element object.Object, elements []object.Object) object.Object {
+	for _, e := range elements {
+		result := evalInfixExpression("==", element, e)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			return TRUE
+		}
+	}
+	return FALSE
+}
+
+// hashKey calcula la clave de un valor para mapas y conjuntos. Además de los
+// tipos Hashable admite tuplas cuyos elementos sean claves válidas e
+// instancias cuya clase defina hash() e igual(otro).
+func hashKey(obj object.Object) (object.HashKey, *object.Error) {
+	switch obj := obj.(type) {
+	case object.Hashable:
+		return obj.HashKey(), nil
+	case *object.Tuple:
+		h := fnv.New64a()
+		var buf [8]byte
+		for _, e := range obj.Elements {
+			key, err := hashKey(e)
+			if err != nil {
+				return object.HashKey{}, err
+			}
+			binary.LittleEndian.PutUint64(buf[:], key.Value)
+			h.Write([]byte(key.Type))
+			h.Write(buf[:])
+		}
+		return object.HashKey{Type: obj.Type(), Value: h.Sum64()}, nil
+	case *object.Instance:
+		if findMethod(obj.Class, "hash") == nil || findMethod(obj.Class, "igual") == nil {
+			return object.HashKey{}, newError("la clase %s debe definir hash() e igual(otro) para usarse como clave", obj.Class.Name)
+		}
+		result := callMethod(obj, "hash")
+		if err, ok := result.(*object.Error); ok {
+			return object.HashKey{}, err
+		}
+		if result.Type() == object.INSTANCE_OBJ {
+			return object.HashKey{}, newError("hash() de %s debe devolver un valor utilizable como clave, se obtuvo %s", obj.Class.Name, result.Type())
+		}
+		key, err := hashKey(result)
+		if err != nil {
+			return object.HashKey{}, err
+		}
+		return object.HashKey{Type: obj.Type(), Value: key.Value}, nil
+	default:
+		return object.HashKey{}, newError("clave no utilizable como hash: %s", obj.Type())
+	}
+}
+
+// KeysEqual indica si dos claves representan el mismo valor en un mapa o
+// conjunto; las instancias se comparan con su método igual(otro)
+func KeysEqual(a, b object.Object) (bool, *object.Error) {
+	switch a := a.(type) {
+	case *object.Instance:
+		other, ok := b.(*object.Instance)
+		if !ok {
+			return false, nil
+		}
+		if a == other {
+			return true, nil
+		}
+		result := callMethod(a, "igual", other)
+		if err, ok := result.(*object.Error); ok {
+			return false, err
+		}
+		return isTruthy(result), nil
+	case *object.Tuple:
+		other, ok := b.(*object.Tuple)
+		if !ok || len(a.Elements) != len(other.Elements) {
+			return false, nil
+		}
+		for i := range a.Elements {
+			equal, err := KeysEqual(a.Elements[i], other.Elements[i])
+			if err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	case *object.EnumMember:
+		// Dos enums con el mismo nombre producen la misma HashKey
+		return a == b, nil
+	}
+
+	switch b.(type) {
+	case *object.Instance, *object.Tuple, *object.EnumMember:
+		return false, nil
+	}
+
+	// Los números comparten HashKey por valor exacto; compararlo evita que
+	// dos fracciones distintas con el mismo hash se tomen por la misma clave
+	if x, ok := exactRat(a); ok {
+		y, ok := exactRat(b)
+		return ok && x.Cmp(y) == 0, nil
+	}
+
+	keyA, err := hashKey(a)
+	if err != nil {
+		return false, err
+	}
+	keyB, err := hashKey(b)
+	if err != nil {
+		return false, err
+	}
+	return keyA == keyB, nil
+}
+
+// findSlot busca la posición de una clave con sondeo lineal: si la posición de
+// su HashKey está ocupada por una clave distinta (dos hash() iguales para
+// valores que no son igual()), prueba la siguiente. Como mapas y conjuntos no
+// eliminan claves en el lugar, las cadenas de sondeo nunca se interrumpen.
+// Devuelve la posición y si la clave ya estaba.
+func findSlot(key object.Object, lookup func(object.HashKey) (object.Object, bool)) (object.HashKey, bool, *object.Error) {
+	slot, err := hashKey(key)
+	if err != nil {
+		return slot, false, err
+	}
+
+	for {
+		existing, ok := lookup(slot)
+		if !ok {
+			return slot, false, nil
+		}
+		equal, err := KeysEqual(existing, key)
+		if err != nil {
+			return slot, false, err
+		}
+		if equal {
+			return slot, true, nil
+		}
+		slot.Value++
+	}
+}
+
+func hashSlot(hash *object.Hash, key object.Object) (object.HashKey, bool, *object.Error) {
+	return findSlot(key, func(slot object.HashKey) (object.Object, bool) {
+		pair, ok := hash.Get(slot)
+		return pair.Key, ok
+	})
+}
+
+// HashPut asocia un valor a una clave del mapa; si la clave ya estaba se
+// conserva la clave original y su posición
+func HashPut(hash *object.Hash, key, value object.Object) *object.Error {
+	slot, found, err := hashSlot(hash, key)
+	if err != nil {
+		return err
+	}
+	if found {
+		pair, _ := hash.Get(slot)
+		key = pair.Key
+	}
+	hash.Put(slot, object.HashPair{Key: key, Value: value})
+	return nil
+}
+
+// SetAdd agrega un elemento al conjunto si no estaba
+func SetAdd(set *object.Set, element object.Object) *object.Error {
+	slot, found, err := findSlot(element, set.Get)
+	if err != nil {
+		return err
+	}
+	if !found {
+		set.Add(slot, element)
+	}
+	return nil
+}
+
+func setContains(set *object.Set, element object.Object) (bool, *object.Error) {
+	_, found, err := findSlot(element, set.Get)
+	return found, err
+}
+
+// NewSet crea un conjunto con los elementos dados; todos deben ser claves válidas
+func NewSet(elements []object.Object) object.Object {
+	set := object.NewSet()
+	for _, e := range elements {
+		if err := SetAdd(set, e); err != nil {
+			return err
+		}
+	}
+	return set
+}
+
+func evalLogicalAndOperator(left, right object.Object) object.Object {
+	if !isTruthy(left) {
+		return left
+	}
+	return right
+}
+
+func evalLogicalOrOperator(left, right object.Object) object.Object {
+	if isTruthy(left) {
+		return left
+	}
+	return right
+}
+
+func evalIfExpression(ie *parser.IfExpression, env *object.Environment) object.Object {
+	condition := Eval(ie.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return Eval(ie.Consequence, env)
+	} else if ie.Alternative != nil {
+		return Eval(ie.Alternative, env)
+	} else {
+		return NULL
+	}
+}
+
+func evalWhileExpression(we *parser.WhileExpression, env *object.Environment) object.Object {
+	var result object.Object = NULL
+
+	for {
+		condition := Eval(we.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			break
+		}
+
+		result = Eval(we.Body, env)
+		if isError(result) {
+			return result
+		}
+
+		// Manejar sentencias de retorno, pero no salir del bucle por ellas
+		if result != nil && result.Type() == object.RETURN_VALUE_OBJ {
+			return result
+		}
+	}
+
+	return result
+}
+
+func evalForExpression(fe *parser.ForExpression, env *object.Environment) object.Object {
+	// Crear un entorno separado para el bucle
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	// Inicialización
+	if fe.Init != nil {
+		initResult := Eval(fe.Init, loopEnv)
+		if isError(initResult) {
+			return initResult
+		}
+	}
+
+	var result object.Object = NULL
 
 	for {
 		// Condición
@@ -419,218 +1495,1472 @@ func evalForExpression(fe *parser.ForExpression, env *object.Environment) object
 			return result
 		}
 
-		// Actualización
-		if fe.Update != nil {
-			updateResult := Eval(fe.Update, loopEnv)
-			if isError(updateResult) {
-				return updateResult
+		// Actualización
+		if fe.Update != nil {
+			updateResult := Eval(fe.Update, loopEnv)
+			if isError(updateResult) {
+				return updateResult
+			}
+		}
+	}
+
+	return result
+}
+
+// evalForInExpression recorre cualquier iterable; cada vuelta tiene su propio
+// entorno con la variable (o las variables desestructuradas) del bucle
+func evalForInExpression(fi *parser.ForInExpression, env *object.Environment) object.Object {
+	iterable := Eval(fi.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var result object.Object = NULL
+
+	err := Iterate(iterable, func(element object.Object) bool {
+		loopEnv := object.NewEnclosedEnvironment(env)
+
+		switch target := fi.Target.(type) {
+		case *parser.Identifier:
+			loopEnv.Set(target.Value, element)
+		case *parser.DestructuringPattern:
+			if err := destructure(target, element, loopEnv); err != nil {
+				result = err
+				return false
+			}
+		}
+
+		result = Eval(fi.Body, loopEnv)
+
+		// Manejar sentencias de retorno y errores, que terminan el bucle
+		return !isError(result) && result.Type() != object.RETURN_VALUE_OBJ
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+// evalComprehension construye una lista, conjunto o mapa por comprensión
+func evalComprehension(node *parser.ComprehensionExpression, env *object.Environment) object.Object {
+	elements := []object.Object{}
+	hash := object.NewHash()
+
+	err := comprehend(node.Clauses, env, func(scope *object.Environment) object.Object {
+		value := Eval(node.Value, scope)
+		if isError(value) {
+			return value
+		}
+		if node.Key == nil {
+			elements = append(elements, value)
+			return nil
+		}
+
+		key := Eval(node.Key, scope)
+		if isError(key) {
+			return key
+		}
+		if err := HashPut(hash, key, value); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case node.Key != nil:
+		return hash
+	case node.Token.Type == lexer.LBRACE:
+		return NewSet(elements)
+	default:
+		return &object.Array{Elements: elements}
+	}
+}
+
+// comprehend recorre la primera cláusula y, por cada elemento que pasa sus
+// filtros, las siguientes; al final de la cadena llama a emit. Cada vuelta
+// usa un entorno encerrado nuevo, así las variables del bucle no quedan
+// definidas fuera de la comprensión. Devuelve nil o el primer error.
+func comprehend(clauses []*parser.ComprehensionClause, env *object.Environment, emit func(*object.Environment) object.Object) object.Object {
+	if len(clauses) == 0 {
+		return emit(env)
+	}
+	clause := clauses[0]
+
+	iterable := Eval(clause.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var result object.Object
+	err := Iterate(iterable, func(element object.Object) bool {
+		scope := object.NewEnclosedEnvironment(env)
+
+		switch target := clause.Target.(type) {
+		case *parser.Identifier:
+			scope.Set(target.Value, element)
+		case *parser.DestructuringPattern:
+			if err := destructure(target, element, scope); err != nil {
+				result = err
+				return false
+			}
+		}
+
+		for _, condition := range clause.Conditions {
+			passed := Eval(condition, scope)
+			if isError(passed) {
+				result = passed
+				return false
+			}
+			if !isTruthy(passed) {
+				return true
+			}
+		}
+
+		result = comprehend(clauses[1:], scope, emit)
+		return result == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+// destructure asigna los elementos de un iterable a los nombres del patrón;
+// ...resto recibe en una lista los elementos sobrantes
+func destructure(pattern *parser.DestructuringPattern, value object.Object, env *object.Environment) *object.Error {
+	it, err := IteratorOf(value)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for i, name := range pattern.Names {
+		element, ok := it.Next()
+		if !ok {
+			return newError("no se puede desestructurar: se esperaban %d valores, se obtuvieron %d", len(pattern.Names), i)
+		}
+		if err, ok := element.(*object.Error); ok {
+			return err
+		}
+		env.Set(name.Value, element)
+	}
+
+	if pattern.Rest == nil {
+		if it.HasNext() {
+			return newError("no se puede desestructurar: hay más de %d valores", len(pattern.Names))
+		}
+		return nil
+	}
+
+	rest := []object.Object{}
+	for element, ok := it.Next(); ok; element, ok = it.Next() {
+		if err, ok := element.(*object.Error); ok {
+			return err
+		}
+		rest = append(rest, element)
+	}
+	env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+
+	return nil
+}
+
+// IteratorOf devuelve un iterador para cualquier valor iterable: listas,
+// tuplas, textos (por carácter), mapas (pares (clave, valor)), conjuntos,
+// iteradores e instancias que sigan el protocolo: un método iterador() o los
+// métodos siguiente() y tiene_siguiente()
+func IteratorOf(obj object.Object) (*object.Iterator, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Iterator:
+		return obj, nil
+	case *object.Array:
+		// Se recorre la lista viva: los elementos agregados durante el bucle también se visitan
+		i := 0
+		return object.NewIterator(func() (object.Object, bool) {
+			if i >= len(obj.Elements) {
+				return nil, false
+			}
+			i++
+			return obj.Elements[i-1], true
+		}), nil
+	case *object.Tuple:
+		return object.NewSliceIterator(obj.Elements), nil
+	case *object.Range:
+		// Los números se generan a medida que se piden; un rango con más de
+		// MaxInt64 elementos no llega a agotarse en la práctica
+		i, length := int64(0), int64(math.MaxInt64)
+		if n, fits := obj.Len(); fits {
+			length = n
+		}
+		return object.NewIterator(func() (object.Object, bool) {
+			if i >= length {
+				return nil, false
+			}
+			i++
+			return &object.Integer{Value: obj.At(i - 1)}, true
+		}), nil
+	case *object.String:
+		chars := []object.Object{}
+		for _, r := range obj.Value {
+			chars = append(chars, &object.String{Value: string(r)})
+		}
+		return object.NewSliceIterator(chars), nil
+	case *object.Hash:
+		pairs := []object.Object{}
+		for _, pair := range obj.Items() {
+			pairs = append(pairs, &object.Tuple{Elements: []object.Object{pair.Key, pair.Value}})
+		}
+		return object.NewSliceIterator(pairs), nil
+	case *object.Set:
+		return object.NewSliceIterator(obj.Items()), nil
+	case *object.Enum:
+		members := make([]object.Object, 0, len(obj.Members))
+		for _, member := range obj.Members {
+			members = append(members, member)
+		}
+		return object.NewSliceIterator(members), nil
+	case *object.Instance:
+		if findMethod(obj.Class, "iterador") != nil {
+			result := callMethod(obj, "iterador")
+			if err, ok := result.(*object.Error); ok {
+				return nil, err
+			}
+			// iterador() puede devolver la propia instancia u otra que tenga
+			// siguiente() y tiene_siguiente(), o cualquier iterable incorporado
+			if inst, ok := result.(*object.Instance); ok {
+				if !isInstanceIterator(inst) {
+					return nil, newError("iterador() de %s debe devolver un objeto con siguiente() y tiene_siguiente()", obj.Class.Name)
+				}
+				return instanceIterator(inst), nil
+			}
+			return IteratorOf(result)
+		}
+		if isInstanceIterator(obj) {
+			return instanceIterator(obj), nil
+		}
+		return nil, newError("la clase %s no es iterable: defina iterador() o siguiente() y tiene_siguiente()", obj.Class.Name)
+	default:
+		return nil, newError("no se puede iterar sobre %s", obj.Type())
+	}
+}
+
+func isInstanceIterator(instance *object.Instance) bool {
+	return findMethod(instance.Class, "siguiente") != nil && findMethod(instance.Class, "tiene_siguiente") != nil
+}
+
+// instanceIterator adapta una instancia con siguiente() y tiene_siguiente() a
+// un iterador incorporado. Un error de esos métodos se entrega como elemento
+// para que quien recorre lo propague.
+func instanceIterator(instance *object.Instance) *object.Iterator {
+	return object.NewIterator(func() (object.Object, bool) {
+		hasNext := callMethod(instance, "tiene_siguiente")
+		if isError(hasNext) {
+			return hasNext, true
+		}
+		if !isTruthy(hasNext) {
+			return nil, false
+		}
+		return callMethod(instance, "siguiente"), true
+	})
+}
+
+// Iterate recorre cualquier valor iterable y llama a fn con cada elemento
+// hasta que no queden más o fn devuelva false. Al terminar cierra el
+// iterador, así un generador abandonado a mitad no deja su gorrutina viva.
+func Iterate(obj object.Object, fn func(object.Object) bool) *object.Error {
+	it, err := IteratorOf(obj)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for element, ok := it.Next(); ok; element, ok = it.Next() {
+		if err, ok := element.(*object.Error); ok {
+			return err
+		}
+		if !fn(element) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Collect devuelve en una lista de Go todos los elementos de un iterable
+func Collect(obj object.Object) ([]object.Object, *object.Error) {
+	elements := []object.Object{}
+	err := Iterate(obj, func(element object.Object) bool {
+		elements = append(elements, element)
+		return true
+	})
+	return elements, err
+}
+
+// RangeLength devuelve la cantidad de elementos de un rango como ENTERO, o un
+// error si son más de los que puede representar
+func RangeLength(r *object.Range) object.Object {
+	length, fits := r.Len()
+	if !fits {
+		return newError("el rango %s tiene demasiados elementos para contarlos", r.Inspect())
+	}
+	return &object.Integer{Value: length}
+}
+
+// evalChain evalúa una cadena de accesos y llamadas (a.b, a[i], a.b()). Si un
+// '?.' o '?[' encuentra nulo, el resto de la cadena no se evalúa y el
+// resultado es nulo; el segundo valor indica que la cadena se cortó.
+func evalChain(node parser.Expression, env *object.Environment) (object.Object, bool) {
+	switch node := node.(type) {
+	case *parser.DotExpression:
+		obj, cut := evalChain(node.Object, env)
+		if cut || isError(obj) {
+			return obj, cut
+		}
+		if node.Optional && obj == NULL {
+			return NULL, true
+		}
+		return evalDotExpression(obj, node.Property.Value), false
+	case *parser.IndexExpression:
+		left, cut := evalChain(node.Left, env)
+		if cut || isError(left) {
+			return left, cut
+		}
+		if node.Optional && left == NULL {
+			return NULL, true
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index, false
+		}
+		return evalIndexExpression(left, index), false
+	case *parser.SliceExpression:
+		left, cut := evalChain(node.Left, env)
+		if cut || isError(left) {
+			return left, cut
+		}
+		if node.Optional && left == NULL {
+			return NULL, true
+		}
+		// Los límites omitidos valen nulo
+		bounds := []object.Object{NULL, NULL, NULL}
+		for i, exp := range []parser.Expression{node.Start, node.End, node.Step} {
+			if exp == nil {
+				continue
+			}
+			bounds[i] = Eval(exp, env)
+			if isError(bounds[i]) {
+				return bounds[i], false
+			}
+		}
+		return evalSliceExpression(left, bounds[0], bounds[1], bounds[2]), false
+	case *parser.CallExpression:
+		function, cut := evalChain(node.Function, env)
+		if cut || isError(function) {
+			return function, cut
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0], false
+		}
+		return applyFunction(function, args), false
+	default:
+		return Eval(node, env), false
+	}
+}
+
+// evalPipeExpression resuelve 'valor |> destino'. Si el destino es una
+// llamada, el valor se pasa como primer argumento o en el lugar de cada '_';
+// si no, el destino debe ser una función y se llama con el valor.
+func evalPipeExpression(value object.Object, target parser.Expression, env *object.Environment) object.Object {
+	call, ok := target.(*parser.CallExpression)
+	if !ok {
+		function := Eval(target, env)
+		if isError(function) {
+			return function
+		}
+		return applyFunction(function, []object.Object{value})
+	}
+
+	function, cut := evalChain(call.Function, env)
+	if cut || isError(function) {
+		return function
+	}
+
+	args := []object.Object{}
+	placed := false
+	for _, arg := range call.Arguments {
+		if ident, ok := arg.(*parser.Identifier); ok && ident.Value == pipePlaceholder {
+			args = append(args, value)
+			placed = true
+			continue
+		}
+		evaluated := evalExpressions([]parser.Expression{arg}, env)
+		if len(evaluated) == 1 && isError(evaluated[0]) {
+			return evaluated[0]
+		}
+		args = append(args, evaluated...)
+	}
+	if !placed {
+		args = append([]object.Object{value}, args...)
+	}
+
+	return applyFunction(function, args)
+}
+
+func evalAssignExpression(node *parser.AssignExpression, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	switch target := node.Target.(type) {
+	case *parser.Identifier:
+		if node.Operator == ":=" {
+			return env.Set(target.Value, val)
+		}
+		return env.Assign(target.Value, val)
+	case *parser.DotExpression:
+		obj := Eval(target.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalPropertyAssignment(obj, target.Property.Value, val)
+	case *parser.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexAssignment(left, index, val)
+	default:
+		return newError("no se puede asignar a %s", node.Target.String())
+	}
+}
+
+func evalPropertyAssignment(obj object.Object, property string, val object.Object) object.Object {
+	// entorno.x = v define la variable x en el entorno
+	if environment, ok := obj.(*object.EnvironmentValue); ok {
+		return environment.Env.Set(property, val)
+	}
+
+	instance, ok := obj.(*object.Instance)
+	if !ok {
+		return newError("asignación de propiedad no soportada para: %s", obj.Type())
+	}
+
+	if setter := findAccessor(instance.Class, property, true); setter != nil {
+		result := applyFunction(bindMethod(instance, setter), []object.Object{val})
+		if isError(result) {
+			return result
+		}
+		return val
+	}
+	if findAccessor(instance.Class, property, false) != nil {
+		return newError("la propiedad %s de %s es de solo lectura", property, instance.Class.Name)
+	}
+
+	instance.Properties[property] = val
+	return val
+}
+
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("el índice de una lista debe ser ENTERO, se obtuvo %s", index.Type())
+		}
+		// Mismas reglas que la lectura: -1 es el último y, fuera de rango, la
+		// asignación no hace nada y vale nulo salvo con indices_estrictos
+		pos, ok := normalizeIndex(idx.Value, int64(len(left.Elements)))
+		if !ok {
+			return indexOutOfRange(index)
+		}
+		left.Elements[pos] = val
+		return val
+	case *object.Hash:
+		if err := HashPut(left, index, val); err != nil {
+			return err
+		}
+		return val
+	case *object.Tuple:
+		return newError("las tuplas son inmutables")
+	case *object.Instance:
+		// obj[i] = v llama a obj.asignar_indice(i, v)
+		if findMethod(left.Class, "asignar_indice") == nil {
+			return newError("asignación por índice no soportada para %s: defina asignar_indice(i, valor)", left.Class.Name)
+		}
+		result := callMethod(left, "asignar_indice", index, val)
+		if isError(result) {
+			return result
+		}
+		return val
+	default:
+		return newError("asignación por índice no soportada para: %s", left.Type())
+	}
+}
+
+func evalIdentifier(node *parser.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+
+	return newError("identificador no encontrado: %s", node.Value)
+}
+
+func evalExpressions(exps []parser.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, e := range exps {
+		// ...iterable aporta todos sus elementos
+		if spread, ok := e.(*parser.SpreadExpression); ok {
+			value := Eval(spread.Value, env)
+			if isError(value) {
+				return []object.Object{value}
+			}
+			err := Iterate(value, func(element object.Object) bool {
+				result = append(result, element)
+				return true
+			})
+			if err != nil {
+				return []object.Object{err}
+			}
+			continue
+		}
+
+		evaluated := Eval(e, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+func applyFunction(fn object.Object, args []object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Function:
+		if fn.IsGenerator {
+			return newGenerator(fn, args)
+		}
+		extendedEnv := extendFunctionEnv(fn, args)
+		evaluated := Eval(fn.Body, extendedEnv)
+		return unwrapReturnValue(evaluated)
+	case *object.Builtin:
+		return fn.Fn(args...)
+	default:
+		return newError("no es una función: %s", fn.Type())
+	}
+}
+
+// yieldBinding es el nombre con el que el entorno de un generador guarda la
+// función que suspende su ejecución; al ser una palabra clave ninguna
+// variable del programa puede ocultarlo
+const yieldBinding = "producir"
+
+// errGeneratorClosed se devuelve desde 'producir' cuando el generador se
+// cierra antes de terminar; se propaga como cualquier error para deshacer la
+// ejecución del cuerpo y nunca llega al programa
+var errGeneratorClosed = &object.Error{Message: "generador cerrado"}
+
+// newGenerator crea el iterador que devuelve una función generadora. El
+// cuerpo se ejecuta en su propia gorrutina, que se alterna con quien recorre
+// el generador: cada 'producir' entrega un valor y espera a que se pida el
+// siguiente. Cerrar el iterador (al terminar antes un bucle 'para' o cuando
+// el recolector de basura lo libera) termina la gorrutina.
+func newGenerator(fn *object.Function, args []object.Object) object.Object {
+	yields := make(chan object.Object)
+	resume := make(chan bool)
+	// running es verdad mientras el cuerpo se ejecuta: si el propio cuerpo
+	// pide el siguiente valor, esperaría en su propio canal para siempre
+	started, finished, running := false, false, false
+
+	run := func() {
+		defer close(yields)
+		if !<-resume {
+			return
+		}
+
+		aborted := false
+		env := extendFunctionEnv(fn, args)
+		env.Set(yieldBinding, &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if aborted {
+				return errGeneratorClosed
+			}
+			yields <- args[0]
+			if !<-resume {
+				aborted = true
+				return errGeneratorClosed
+			}
+			return NULL
+		}})
+
+		// Un error del cuerpo se entrega como último elemento
+		result := Eval(fn.Body, env)
+		if isError(result) && !aborted {
+			yields <- result
+			<-resume
+		}
+	}
+
+	next := func() (object.Object, bool) {
+		if finished {
+			return nil, false
+		}
+		if running {
+			return newError("el generador ya se está ejecutando"), true
+		}
+		if !started {
+			started = true
+			go run()
+		}
+		running = true
+		resume <- true
+		value, ok := <-yields
+		running = false
+		if !ok {
+			finished = true
+			return nil, false
+		}
+		return value, true
+	}
+
+	stop := func() {
+		// Desde su propio cuerpo no se puede cerrar: se cierra al terminar
+		if running {
+			return
+		}
+		if started && !finished {
+			resume <- false
+			for range yields {
+			}
+		}
+		finished = true
+	}
+
+	it := object.NewClosableIterator(next, stop)
+	runtime.SetFinalizer(it, (*object.Iterator).Close)
+	return it
+}
+
+func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	env := object.NewEnclosedEnvironment(fn.Env)
+
+	for paramIdx, param := range fn.Parameters {
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+		} else {
+			// Si no hay suficientes argumentos, asignar NULL
+			env.Set(param.Value, NULL)
+		}
+	}
+
+	return env
+}
+
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
+
+// strictIndexing hace que un índice fuera de rango sea un error en lugar de
+// devolver nulo; se activa con indices_estrictos(verdad)
+var strictIndexing = false
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	// obj[i] llama a obj.indice(i)
+	if instance, ok := left.(*object.Instance); ok && findMethod(instance.Class, "indice") != nil {
+		return callMethod(instance, "indice", index)
+	}
+
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.TUPLE_OBJ && index.Type() == object.INTEGER_OBJ:
+		tuple := left.(*object.Tuple)
+		idx, ok := normalizeIndex(index.(*object.Integer).Value, int64(len(tuple.Elements)))
+		if !ok {
+			return indexOutOfRange(index)
+		}
+		return tuple.Elements[idx]
+	case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+		element, ok := left.(*object.Range).Index(index.(*object.Integer).Value)
+		if !ok {
+			return indexOutOfRange(index)
+		}
+		return &object.Integer{Value: element}
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("operador de índice no soportado: %s", left.Type())
+	}
+}
+
+func evalArrayIndexExpression(array, index object.Object) object.Object {
+	arrayObject := array.(*object.Array)
+
+	idx, ok := normalizeIndex(index.(*object.Integer).Value, int64(len(arrayObject.Elements)))
+	if !ok {
+		return indexOutOfRange(index)
+	}
+
+	return arrayObject.Elements[idx]
+}
+
+// evalStringIndexExpression indexa un texto por caracteres (runas), no por bytes
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+
+	idx, ok := normalizeIndex(index.(*object.Integer).Value, int64(len(runes)))
+	if !ok {
+		return indexOutOfRange(index)
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// normalizeIndex convierte un índice negativo en uno contado desde el final
+// (-1 es el último) e indica si queda dentro del rango
+func normalizeIndex(idx, length int64) (int64, bool) {
+	if idx < 0 {
+		idx += length
+	}
+	return idx, idx >= 0 && idx < length
+}
+
+func indexOutOfRange(index object.Object) object.Object {
+	if strictIndexing {
+		return newError("índice fuera de rango: %s", index.Inspect())
+	}
+	return NULL
+}
+
+// evalSliceExpression devuelve una lista o texto nuevo con los elementos de
+// inicio (incluido) a fin (excluido) cada 'paso', con las mismas reglas que
+// Python: índices negativos desde el final y límites recortados al tamaño.
+// La porción de un rango es otro rango.
+func evalSliceExpression(left, start, end, step object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Range:
+		length, fits := left.Len()
+		if !fits {
+			return newError("el rango %s tiene demasiados elementos para tomar una porción", left.Inspect())
+		}
+		startVal, _, stepVal, count, err := sliceBounds(length, start, end, step)
+		if err != nil {
+			return err
+		}
+		first := left.At(startVal)
+		if count == 0 {
+			return &object.Range{Start: first, Stop: first, Step: 1}
+		}
+		newStep := left.Step * stepVal
+		if count > 1 && newStep/stepVal != left.Step {
+			return newError("el paso de la porción de %s no cabe en un ENTERO", left.Inspect())
+		}
+		// La porción termina en su último elemento, que siempre cabe en un ENTERO
+		return &object.Range{Start: first, Stop: first + (count-1)*newStep, Step: newStep, Inclusive: true}
+	case *object.Array:
+		indices, err := sliceIndices(int64(len(left.Elements)), start, end, step)
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, 0, len(indices))
+		for _, i := range indices {
+			elements = append(elements, left.Elements[i])
+		}
+		return &object.Array{Elements: elements}
+	case *object.Tuple:
+		indices, err := sliceIndices(int64(len(left.Elements)), start, end, step)
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, 0, len(indices))
+		for _, i := range indices {
+			elements = append(elements, left.Elements[i])
+		}
+		return &object.Tuple{Elements: elements}
+	case *object.String:
+		runes := []rune(left.Value)
+		indices, err := sliceIndices(int64(len(runes)), start, end, step)
+		if err != nil {
+			return err
+		}
+		result := make([]rune, 0, len(indices))
+		for _, i := range indices {
+			result = append(result, runes[i])
+		}
+		return &object.String{Value: string(result)}
+	default:
+		return newError("operador de porción no soportado: %s", left.Type())
+	}
+}
+
+// sliceBounds normaliza inicio, fin y paso de una porción sobre una secuencia
+// de la longitud indicada y devuelve también la cantidad de elementos
+func sliceBounds(length int64, start, end, step object.Object) (int64, int64, int64, int64, *object.Error) {
+	bound := func(obj object.Object, name string) (int64, bool, *object.Error) {
+		switch obj := obj.(type) {
+		case *object.Null:
+			return 0, false, nil
+		case *object.Integer:
+			return obj.Value, true, nil
+		default:
+			return 0, false, newError("el %s de una porción debe ser ENTERO, se obtuvo %s", name, obj.Type())
+		}
+	}
+
+	stepVal, hasStep, err := bound(step, "paso")
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if !hasStep {
+		stepVal = 1
+	}
+	if stepVal == 0 {
+		return 0, 0, 0, 0, newError("el paso de una porción no puede ser cero")
+	}
+
+	// Límites válidos según la dirección del recorrido
+	lower, upper := int64(0), length
+	if stepVal < 0 {
+		lower, upper = -1, length-1
+	}
+
+	clamp := func(obj object.Object, name string, fallback int64) (int64, *object.Error) {
+		value, ok, err := bound(obj, name)
+		if err != nil || !ok {
+			return fallback, err
+		}
+		if value < 0 {
+			value += length
+		}
+		return min(max(value, lower), upper), nil
+	}
+
+	startDefault, endDefault := lower, upper
+	if stepVal < 0 {
+		startDefault, endDefault = upper, lower
+	}
+
+	startVal, err := clamp(start, "inicio", startDefault)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	endVal, err := clamp(end, "fin", endDefault)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	// La distancia siempre cabe en un int64, pero el paso puede ser cualquiera:
+	// su magnitud se toma sin signo, porque -math.MinInt64 se desborda
+	var dist int64
+	if stepVal > 0 && endVal > startVal {
+		dist = endVal - startVal
+	} else if stepVal < 0 && startVal > endVal {
+		dist = startVal - endVal
+	}
+	var count int64
+	if dist > 0 {
+		stepSize := uint64(stepVal)
+		if stepVal < 0 {
+			stepSize = -stepSize
+		}
+		count = int64(uint64(dist-1)/stepSize) + 1
+	}
+
+	return startVal, endVal, stepVal, count, nil
+}
+
+// sliceIndices devuelve las posiciones seleccionadas por una porción
+func sliceIndices(length int64, start, end, step object.Object) ([]int64, *object.Error) {
+	startVal, _, stepVal, count, err := sliceBounds(length, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make([]int64, 0, count)
+	for i := int64(0); i < count; i++ {
+		indices = append(indices, startVal+i*stepVal)
+	}
+
+	return indices, nil
+}
+
+func evalHashLiteral(node *parser.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, keyNode := range node.Keys {
+		key := Eval(keyNode, env)
+		if isError(key) {
+			return key
+		}
+
+		value := Eval(node.Pairs[keyNode], env)
+		if isError(value) {
+			return value
+		}
+
+		if err := HashPut(hash, key, value); err != nil {
+			return err
+		}
+	}
+
+	return hash
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	slot, found, err := hashSlot(hashObject, index)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return NULL
+	}
+
+	pair, _ := hashObject.Get(slot)
+	return pair.Value
+}
+
+func evalDotExpression(obj object.Object, property string) object.Object {
+	// Toda colección incorporada ofrece iterador()
+	if _, isInstance := obj.(*object.Instance); !isInstance && property == "iterador" {
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			it, err := IteratorOf(obj)
+			if err != nil {
+				return err
+			}
+			return it
+		}}
+	}
+
+	switch obj := obj.(type) {
+	case *object.Instance:
+		// Las propiedades calculadas tienen prioridad sobre los campos
+		if getter := findAccessor(obj.Class, property, false); getter != nil {
+			return applyFunction(bindMethod(obj, getter), nil)
+		}
+
+		// esto.crear(...) delega en otra sobrecarga del constructor
+		if property == "crear" {
+			return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+				return construct(obj, obj.Class, args)
+			}}
+		}
+
+		// Buscar propiedad en la instancia
+		if val, ok := obj.Properties[property]; ok {
+			return val
+		}
+
+		// Buscar método en la clase
+		if method := findMethod(obj.Class, property); method != nil {
+			return bindMethod(obj, method)
+		}
+
+		return newError("propiedad o método no encontrado: %s", property)
+	case *object.String:
+		// Añadir métodos incorporados para strings
+		switch property {
+		case "longitud":
+			return &object.Integer{Value: int64(utf8.RuneCountInString(obj.Value))}
+		// Añadir más métodos de string según sea necesario
+		}
+		return newError("propiedad no encontrada en string: %s", property)
+	case *object.Array:
+		// Añadir métodos incorporados para arrays
+		switch property {
+		case "longitud":
+			return &object.Integer{Value: int64(len(obj.Elements))}
+		// Añadir más métodos de array según sea necesario
+		}
+		return newError("propiedad no encontrada en array: %s", property)
+	case *object.Set:
+		switch property {
+		case "longitud":
+			return &object.Integer{Value: int64(obj.Len())}
+		}
+		return newError("propiedad no encontrada en conjunto: %s", property)
+	case *object.Tuple:
+		switch property {
+		case "longitud":
+			return &object.Integer{Value: int64(len(obj.Elements))}
+		}
+		return newError("propiedad no encontrada en tupla: %s", property)
+	case *object.Enum:
+		if member, ok := obj.Member(property); ok {
+			return member
+		}
+		if property == "longitud" {
+			return &object.Integer{Value: int64(len(obj.Members))}
+		}
+		return newError("el enum %s no tiene el miembro %s", obj.Name, property)
+	case *object.Module:
+		if val, ok := obj.Export(property); ok {
+			return val
+		}
+		return newError("el módulo %s no exporta %s", obj.Name, property)
+	case *object.EnvironmentValue:
+		// entorno.x lee la variable x del entorno
+		if val, ok := obj.Env.Get(property); ok {
+			return val
+		}
+		return newError("identificador no encontrado en el entorno: %s", property)
+	case *object.EnumMember:
+		switch property {
+		case "nombre":
+			return &object.String{Value: obj.Name}
+		case "valor":
+			return &object.Integer{Value: obj.Ordinal}
+		case "enum":
+			return obj.Enum
+		}
+		return newError("propiedad no encontrada en %s: %s", obj.Inspect(), property)
+	case *object.Range:
+		switch property {
+		case "longitud":
+			return RangeLength(obj)
+		case "inicio":
+			return &object.Integer{Value: obj.Start}
+		case "fin":
+			return &object.Integer{Value: obj.Stop}
+		case "paso":
+			return &object.Integer{Value: obj.Step}
+		case "inclusivo":
+			return nativeBoolToBooleanObject(obj.Inclusive)
+		}
+		return newError("propiedad no encontrada en rango: %s", property)
+	case *object.Iterator:
+		switch property {
+		case "tiene_siguiente":
+			return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+				return nativeBoolToBooleanObject(obj.HasNext())
+			}}
+		case "siguiente":
+			return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+				element, ok := obj.Next()
+				if !ok {
+					return newError("el iterador no tiene más elementos")
+				}
+				return element
+			}}
+		case "cerrar":
+			return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+				obj.Close()
+				return NULL
+			}}
+		}
+		return newError("propiedad no encontrada en iterador: %s", property)
+	default:
+		return newError("acceso a propiedad no soportado para: %s", obj.Type())
+	}
+}
+
+// EvalSource analiza y evalúa código fuente en el entorno dado, igual que el
+// intérprete con un archivo. Si hay errores de sintaxis no evalúa nada y los
+// devuelve.
+func EvalSource(source string, env *object.Environment) (object.Object, []string) {
+	p := parser.New(lexer.New(source))
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, p.Errors()
+	}
+
+	return Eval(program, env), nil
+}
+
+// moduleDirBinding guarda en el entorno de cada módulo el directorio de su
+// archivo, para resolver sus propias importaciones
+const moduleDirBinding = "<directorio>"
+
+// modules guarda los módulos ya cargados por ruta absoluta; importStack las
+// rutas que se están cargando, para detectar importaciones circulares
+var (
+	modules     = map[string]*object.Module{}
+	importStack []string
+)
+
+func evalImportStatement(node *parser.ImportStatement, env *object.Environment) object.Object {
+	module, err := importModule(node.Path, env)
+	if err != nil {
+		return err
+	}
+
+	if node.Alias != nil {
+		env.Set(node.Alias.Value, module)
+		return module
+	}
+
+	for _, name := range node.Names {
+		val, ok := module.Export(name.Value)
+		if !ok {
+			return newError("el módulo %s no exporta %s", module.Name, name.Value)
+		}
+		env.Set(name.Value, val)
+	}
+	return module
+}
+
+// importModule resuelve la ruta respecto al archivo que importa y devuelve el
+// módulo, evaluándolo solo la primera vez
+func importModule(path string, env *object.Environment) (*object.Module, *object.Error) {
+	if filepath.Ext(path) == "" {
+		path += ".gaby"
+	}
+	if !filepath.IsAbs(path) {
+		dir, ok := env.Get(moduleDirBinding)
+		if ok {
+			path = filepath.Join(dir.(*object.String).Value, path)
+		} else if cwd, err := os.Getwd(); err == nil {
+			path = filepath.Join(cwd, path)
+		}
+	}
+	path = filepath.Clean(path)
+
+	if module, ok := modules[path]; ok {
+		return module, nil
+	}
+
+	for i, loading := range importStack {
+		if loading == path {
+			chain := []string{}
+			for _, p := range append(importStack[i:], path) {
+				chain = append(chain, filepath.Base(p))
 			}
+			return nil, newError("importación circular: %s", strings.Join(chain, " -> "))
 		}
 	}
 
-	return result
-}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newError("no se pudo importar %s: %s", path, err)
+	}
 
-func evalIdentifier(node *parser.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
-		return val
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("errores de sintaxis en %s:\n\t- %s", path, strings.Join(p.Errors(), "\n\t- "))
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
-		return builtin
+	// El módulo ve la biblioteca estándar pero no las variables de quien lo importa
+	moduleEnv := object.NewEnclosedEnvironment(env.Root())
+	moduleEnv.Set(moduleDirBinding, &object.String{Value: filepath.Dir(path)})
+
+	importStack = append(importStack, path)
+	result := Eval(program, moduleEnv)
+	importStack = importStack[:len(importStack)-1]
+
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, newError("en el módulo %s: %s", filepath.Base(path), errObj.Message)
 	}
 
-	return newError("identificador no encontrado: " + node.Value)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return cacheModule(&object.Module{Name: name, Path: path, Env: moduleEnv}, program), nil
 }
 
-func evalExpressions(exps []parser.Expression, env *object.Environment) []object.Object {
-	var result []object.Object
+// cacheModule fija los nombres públicos del módulo y lo guarda en la caché.
+// Si el módulo usa 'exportar', solo esos nombres son públicos; si no, todos
+// los que no empiezan por '_'.
+func cacheModule(module *object.Module, program *parser.Program) *object.Module {
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*parser.ExportStatement); ok {
+			module.Exports = append(module.Exports, export.Names...)
+		}
+	}
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
-		if isError(evaluated) {
-			return []object.Object{evaluated}
+	if module.Exports == nil {
+		for _, name := range module.Env.Names() {
+			if name != moduleDirBinding && !strings.HasPrefix(name, "_") {
+				module.Exports = append(module.Exports, name)
+			}
 		}
-		result = append(result, evaluated)
 	}
+	sort.Strings(module.Exports)
 
-	return result
+	modules[module.Path] = module
+	return module
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
-	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
-	case *object.Builtin:
-		return fn.Fn(args...)
-	default:
-		return newError("no es una función: %s", fn.Type())
-	}
+// GetProperty lee obj.nombre como lo haría el programa: campos, propiedades
+// calculadas y métodos enlazados a la instancia
+func GetProperty(obj object.Object, name string) object.Object {
+	return evalDotExpression(obj, name)
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
-
-	for paramIdx, param := range fn.Parameters {
-		if paramIdx < len(args) {
-			env.Set(param.Value, args[paramIdx])
-		} else {
-			// Si no hay suficientes argumentos, asignar NULL
-			env.Set(param.Value, NULL)
-		}
-	}
+// SetProperty asigna obj.nombre = valor, pasando por 'asignar' si la
+// propiedad es calculada
+func SetProperty(obj object.Object, name string, value object.Object) object.Object {
+	return evalPropertyAssignment(obj, name, value)
+}
 
-	return env
+// ApplyFunction llama a una función de Gaby o incorporada con los argumentos dados
+func ApplyFunction(fn object.Object, args []object.Object) object.Object {
+	return applyFunction(fn, args)
 }
 
-func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+// MethodNames devuelve en orden alfabético los métodos de una clase, incluidos
+// los heredados y los de sus rasgos
+func MethodNames(class *object.Class) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for c := class; c != nil; c = c.Parent {
+		for name := range c.Methods {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
 	}
-	return obj
+	sort.Strings(names)
+	return names
 }
 
-func evalIndexExpression(left, index object.Object) object.Object {
-	switch {
-	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
-		return evalArrayIndexExpression(left, index)
-	case left.Type() == object.HASH_OBJ:
-		return evalHashIndexExpression(left, index)
-	default:
-		return newError("operador de índice no soportado: %s", left.Type())
+// findMethod busca un método en la clase y, si no lo tiene, en sus clases padre
+func findMethod(class *object.Class, name string) *object.Function {
+	for c := class; c != nil; c = c.Parent {
+		if method, ok := c.Methods[name]; ok {
+			return method
+		}
 	}
+	return nil
 }
 
-func evalArrayIndexExpression(array, index object.Object) object.Object {
-	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
+func evalTraitLiteral(node *parser.TraitLiteral, env *object.Environment) object.Object {
+	trait := &object.Trait{
+		Name:    node.Name.Value,
+		Methods: make(map[string]*object.Function),
+	}
 
-	if idx < 0 || idx > max {
-		return NULL
+	for _, methodNode := range node.Methods {
+		trait.Methods[methodNode.Name] = &object.Function{
+			Parameters:  methodNode.Parameters,
+			Body:        methodNode.Body,
+			Env:         object.NewEnclosedEnvironment(env),
+			Name:        methodNode.Name,
+			IsGenerator: methodNode.IsGenerator,
+		}
+	}
+	for _, required := range node.Required {
+		trait.Required = append(trait.Required, required.Name)
 	}
 
-	return arrayObject.Elements[idx]
+	env.Set(node.Name.Value, trait)
+
+	return trait
 }
 
-func evalHashLiteral(node *parser.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+// includeTraits copia en la clase los métodos de los rasgos de 'usa'. Un
+// método definido en la propia clase tiene prioridad sobre el de un rasgo, y
+// el de un rasgo sobre el heredado del padre. Si dos rasgos aportan métodos
+// distintos con el mismo nombre y la clase no lo define, es ambiguo. Los
+// métodos requeridos deben existir en la clase, sus padres u otro rasgo; en
+// una clase abstracta pasan a ser abstractos.
+func includeTraits(class *object.Class, names []*parser.Identifier, env *object.Environment) *object.Error {
+	own := make(map[string]bool, len(class.Methods))
+	for name := range class.Methods {
+		own[name] = true
+	}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
-		if isError(key) {
-			return key
+	providers := map[string]*object.Trait{}
+	for _, name := range names {
+		value := Eval(name, env)
+		if err, ok := value.(*object.Error); ok {
+			return err
 		}
-
-		hashKey, ok := key.(object.Hashable)
+		trait, ok := value.(*object.Trait)
 		if !ok {
-			return newError("clave no utilizable como hash: %s", key.Type())
+			return newError("%s no es un rasgo: no se puede usar en la clase %s", name.Value, class.Name)
 		}
+		class.Traits = append(class.Traits, trait)
 
-		value := Eval(valueNode, env)
-		if isError(value) {
-			return value
+		for _, methodName := range trait.MethodNames() {
+			if own[methodName] {
+				continue
+			}
+			method := trait.Methods[methodName]
+			if previous, ok := providers[methodName]; ok && previous.Methods[methodName] != method {
+				return newError("método ambiguo %s en la clase %s: lo definen los rasgos %s y %s; defínalo en la clase",
+					methodName, class.Name, previous.Name, trait.Name)
+			}
+			providers[methodName] = trait
+			class.Methods[methodName] = method
 		}
-
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
-	}
-
-	return &object.Hash{Pairs: pairs}
-}
-
-func evalHashIndexExpression(hash, index object.Object) object.Object {
-	hashObject := hash.(*object.Hash)
-
-	key, ok := index.(object.Hashable)
-	if !ok {
-		return newError("clave no utilizable como hash: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
-	if !ok {
-		return NULL
+	for _, trait := range class.Traits {
+		for _, required := range trait.Required {
+			if findMethod(class, required) != nil || (required == "crear" && hasConstructor(class)) {
+				continue
+			}
+			if !class.Abstract {
+				return newError("la clase %s debe implementar %s, requerido por el rasgo %s", class.Name, required, trait.Name)
+			}
+			class.AbstractMethods = append(class.AbstractMethods, required)
+		}
 	}
 
-	return pair.Value
+	return nil
 }
 
-func evalDotExpression(obj object.Object, property string) object.Object {
-	switch obj := obj.(type) {
-	case *object.Instance:
-		// Buscar propiedad en la instancia
-		if val, ok := obj.Properties[property]; ok {
-			return val
+// unimplementedMethods devuelve los métodos abstractos heredados que ni la
+// clase ni sus padres implementan. Cuenta la definición más cercana: un
+// método concreto de una clase intermedia implementa el abstracto de sus
+// padres. Como los demás métodos, crear se compara solo por nombre.
+func unimplementedMethods(class *object.Class) []string {
+	defined := map[string]bool{}
+	missing := []string{}
+	for c := class; c != nil; c = c.Parent {
+		for name := range c.Methods {
+			defined[name] = true
 		}
-
-		// Buscar método en la clase
-		if method, ok := obj.Class.Methods[property]; ok {
-			// Enlazar el método a esta instancia (this/esto)
-			boundMethod := &object.Function{
-				Parameters: method.Parameters,
-				Body:       method.Body,
-				Env:        method.Env,
-				Name:       method.Name,
+		if len(c.Constructors) > 0 {
+			defined["crear"] = true
+		}
+		for _, name := range c.AbstractMethods {
+			if !defined[name] {
+				defined[name] = true
+				missing = append(missing, name)
 			}
-
-			// Crear un entorno para el método con 'esto' configurado
-			methodEnv := object.NewEnclosedEnvironment(obj.Env)
-			methodEnv.Set("esto", obj)
-			boundMethod.Env = methodEnv
-
-			return boundMethod
 		}
+	}
+	return missing
+}
 
-		return newError("propiedad o método no encontrado: %s", property)
-	case *object.String:
-		// Añadir métodos incorporados para strings
-		switch property {
-		case "longitud":
-			return &object.Integer{Value: int64(len(obj.Value))}
-		// Añadir más métodos de string según sea necesario
+// findAccessor busca el obtener (o el asignar) de una propiedad calculada
+// en la clase y en sus clases padre
+func findAccessor(class *object.Class, name string, setter bool) *object.Function {
+	for c := class; c != nil; c = c.Parent {
+		accessors := c.Getters
+		if setter {
+			accessors = c.Setters
 		}
-		return newError("propiedad no encontrada en string: %s", property)
-	case *object.Array:
-		// Añadir métodos incorporados para arrays
-		switch property {
-		case "longitud":
-			return &object.Integer{Value: int64(len(obj.Elements))}
-		// Añadir más métodos de array según sea necesario
+		if accessor, ok := accessors[name]; ok {
+			return accessor
 		}
-		return newError("propiedad no encontrada en array: %s", property)
-	default:
-		return newError("acceso a propiedad no soportado para: %s", obj.Type())
 	}
+	return nil
+}
+
+// bindMethod enlaza un método a una instancia (this/esto)
+func bindMethod(instance *object.Instance, method *object.Function) *object.Function {
+	// Crear un entorno para el método con 'esto' configurado
+	methodEnv := object.NewEnclosedEnvironment(instance.Env)
+	methodEnv.Set("esto", instance)
+
+	return &object.Function{
+		Parameters:  method.Parameters,
+		Body:        method.Body,
+		Env:         methodEnv,
+		Name:        method.Name,
+		IsGenerator: method.IsGenerator,
+	}
+}
+
+// callMethod llama a un método de la instancia con los argumentos dados
+func callMethod(instance *object.Instance, name string, args ...object.Object) object.Object {
+	method := findMethod(instance.Class, name)
+	if method == nil {
+		return newError("método no encontrado en %s: %s", instance.Class.Name, name)
+	}
+	return applyFunction(bindMethod(instance, method), args)
 }
 
 func evalClassLiteral(node *parser.ClassLiteral, env *object.Environment) object.Object {
 	class := &object.Class{
 		Name:       node.Name.Value,
-		Properties: make(map[string]object.Object),
+		Fields:     node.Properties,
+		Env:        env,
 		Methods:    make(map[string]*object.Function),
+		Getters:    make(map[string]*object.Function),
+		Setters:    make(map[string]*object.Function),
+		Abstract:   node.Abstract,
+	}
+
+	if !node.Abstract && len(node.AbstractMethods) > 0 {
+		return newError("la clase %s declara métodos abstractos: debe ser 'abstracta clase'", node.Name.Value)
+	}
+	for _, method := range node.AbstractMethods {
+		class.AbstractMethods = append(class.AbstractMethods, method.Name)
+	}
+
+	if node.Parent != nil {
+		parent := Eval(node.Parent, env)
+		if isError(parent) {
+			return parent
+		}
+		parentClass, ok := parent.(*object.Class)
+		if !ok {
+			return newError("%s no es una clase: no se puede extender", node.Parent.Value)
+		}
+		class.Parent = parentClass
 	}
 
-	// Procesar propiedades
+	// Los campos se evalúan al crear cada instancia; aquí solo se validan
 	for _, propNode := range node.Properties {
-		propValue := Eval(propNode.Value, env)
-		if isError(propValue) {
-			return propValue
+		if propNode.Pattern != nil {
+			return newError("los campos de la clase %s no admiten desestructuración: %s", node.Name.Value, propNode.Pattern.String())
+		}
+	}
+
+	newMethod := func(methodNode *parser.FunctionLiteral) *object.Function {
+		return &object.Function{
+			Parameters:  methodNode.Parameters,
+			Body:        methodNode.Body,
+			Env:         object.NewEnclosedEnvironment(env),
+			Name:        methodNode.Name,
+			IsGenerator: methodNode.IsGenerator,
 		}
-		class.Properties[propNode.Name.Value] = propValue
 	}
 
-	// Procesar métodos
+	// Procesar métodos; cada 'crear' es una sobrecarga del constructor
 	for _, methodNode := range node.Methods {
-		methodEnv := object.NewEnclosedEnvironment(env)
-		method := &object.Function{
-			Parameters: methodNode.Parameters,
-			Body:       methodNode.Body,
-			Env:        methodEnv,
-			Name:       methodNode.Name,
+		if methodNode.Name != "crear" {
+			class.Methods[methodNode.Name] = newMethod(methodNode)
+			continue
+		}
+		for _, other := range class.Constructors {
+			if len(other.Parameters) == len(methodNode.Parameters) {
+				return newError("la clase %s define dos veces crear con %d parámetro(s)", class.Name, len(methodNode.Parameters))
+			}
+		}
+		class.Constructors = append(class.Constructors, newMethod(methodNode))
+	}
+
+	// Procesar propiedades calculadas
+	for _, getterNode := range node.Getters {
+		class.Getters[getterNode.Name] = newMethod(getterNode)
+	}
+	for _, setterNode := range node.Setters {
+		class.Setters[setterNode.Name] = newMethod(setterNode)
+	}
+
+	if err := includeTraits(class, node.Traits, env); err != nil {
+		return err
+	}
+
+	if !class.Abstract {
+		if missing := unimplementedMethods(class); len(missing) > 0 {
+			return newError("la clase %s debe implementar los métodos abstractos: %s", class.Name, strings.Join(missing, ", "))
 		}
-		class.Methods[methodNode.Name] = method
 	}
 
 	// Almacenar la clase en el entorno
@@ -639,6 +2969,112 @@ func evalClassLiteral(node *parser.ClassLiteral, env *object.Environment) object
 	return class
 }
 
+func evalEnumLiteral(node *parser.EnumLiteral, env *object.Environment) object.Object {
+	enum := &object.Enum{Name: node.Name.Value}
+	for i, member := range node.Members {
+		enum.Members = append(enum.Members, &object.EnumMember{Enum: enum, Name: member.Value, Ordinal: int64(i)})
+	}
+
+	env.Set(node.Name.Value, enum)
+
+	return enum
+}
+
+// warnedSwitches recuerda los 'evaluar' que ya advirtieron de casos faltantes,
+// para no repetir la advertencia en cada vuelta de un bucle
+var warnedSwitches = map[*parser.SwitchExpression]bool{}
+
+// evalSwitchExpression ejecuta la primera rama cuyo valor es igual (==) al
+// evaluado, o 'defecto' si ninguna coincide. Los valores de las ramas se
+// evalúan en orden y solo hasta la primera coincidencia, así los que vienen
+// después no ejecutan efectos ni pueden fallar.
+func evalSwitchExpression(node *parser.SwitchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	if member, ok := subject.(*object.EnumMember); ok && node.Default == nil && !warnedSwitches[node] {
+		warnMissingEnumCases(node, member.Enum, env)
+	}
+
+	var body *parser.BlockStatement
+cases:
+	for _, c := range node.Cases {
+		for _, valueNode := range c.Values {
+			value := Eval(valueNode, env)
+			if isError(value) {
+				return value
+			}
+			equal, err := objectsEqual(subject, value, map[visitedPair]bool{})
+			if err != nil {
+				return err
+			}
+			if equal {
+				body = c.Body
+				break cases
+			}
+		}
+	}
+
+	if body == nil {
+		body = node.Default
+	}
+	if body == nil {
+		return NULL
+	}
+	return Eval(body, env)
+}
+
+// warnMissingEnumCases advierte de los miembros del enum que ningún 'cuando'
+// nombra. Como las ramas no se evalúan todas, los miembros cubiertos se leen
+// del código: solo cuentan los valores que se resuelven sin efectos, como
+// Color.Rojo, modulo.Color.Rojo o una variable que guarda el miembro.
+func warnMissingEnumCases(node *parser.SwitchExpression, enum *object.Enum, env *object.Environment) {
+	covered := map[*object.EnumMember]bool{}
+	for _, c := range node.Cases {
+		for _, valueNode := range c.Values {
+			if member, ok := staticValue(valueNode, env).(*object.EnumMember); ok {
+				covered[member] = true
+			}
+		}
+	}
+
+	missing := []string{}
+	for _, m := range enum.Members {
+		if !covered[m] {
+			missing = append(missing, m.Name)
+		}
+	}
+	if len(missing) > 0 {
+		warnedSwitches[node] = true
+		fmt.Fprintf(os.Stderr, "advertencia: línea %d: 'evaluar' sobre %s no cubre %s\n",
+			node.Token.Line, enum.Name, strings.Join(missing, ", "))
+	}
+}
+
+// staticValue resuelve nombres y accesos a miembros de enums y exportaciones
+// de módulos sin ejecutar nada; devuelve nil para cualquier otra expresión
+func staticValue(node parser.Expression, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *parser.Identifier:
+		value, _ := env.Get(node.Value)
+		return value
+	case *parser.DotExpression:
+		switch container := staticValue(node.Object, env).(type) {
+		case *object.Enum:
+			if member, ok := container.Member(node.Property.Value); ok {
+				return member
+			}
+		case *object.Module:
+			if value, ok := container.Export(node.Property.Value); ok {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
 func evalNewExpression(node *parser.NewExpression, env *object.Environment) object.Object {
 	classObj := Eval(node.Class, env)
 	if isError(classObj) {
@@ -649,6 +3085,15 @@ func evalNewExpression(node *parser.NewExpression, env *object.Environment) obje
 	if !ok {
 		return newError("no es una clase: %s", classObj.Type())
 	}
+	if class.Abstract {
+		return newError("no se puede instanciar la clase abstracta %s", class.Name)
+	}
+
+	// Preparar los argumentos
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
 
 	// Crear un nuevo entorno para la instancia
 	instanceEnv := object.NewEnclosedEnvironment(env)
@@ -663,35 +3108,91 @@ func evalNewExpression(node *parser.NewExpression, env *object.Environment) obje
 	// Configurar 'esto' para referir a la instancia
 	instanceEnv.Set("esto", instance)
 
-	// Copiar propiedades de la clase a la instancia
-	for name, value := range class.Properties {
-		instance.Properties[name] = value
+	if err := initializeFields(instance); err != nil {
+		return err
 	}
 
-	// Llamar al constructor si existe
-	if constructor, ok := class.Methods["crear"]; ok {
-		// Preparar los argumentos
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+	// Un error en el constructor descarta la instancia a medio construir
+	if result := construct(instance, class, args); isError(result) {
+		return result
+	}
+
+	return instance
+}
+
+// initializeFields evalúa los campos de la clase para una instancia nueva,
+// primero los de las clases padre y en el orden en que se declararon. Cada
+// instancia recibe valores propios: 'guarda items = []' no se comparte.
+// Un campo puede usar los anteriores con 'esto'.
+func initializeFields(instance *object.Instance) *object.Error {
+	chain := []*object.Class{}
+	for c := instance.Class; c != nil; c = c.Parent {
+		chain = append([]*object.Class{c}, chain...)
+	}
+
+	for _, class := range chain {
+		fieldEnv := object.NewEnclosedEnvironment(class.Env)
+		fieldEnv.Set("esto", instance)
+		for _, field := range class.Fields {
+			value := Eval(field.Value, fieldEnv)
+			if err, ok := value.(*object.Error); ok {
+				return err
+			}
+			instance.Properties[field.Name.Value] = value
 		}
+	}
 
-		// Configurar el entorno del constructor
-		constructorEnv := object.NewEnclosedEnvironment(constructor.Env)
-		constructorEnv.Set("esto", instance)
+	return nil
+}
 
-		// Configurar los parámetros del constructor
-		for paramIdx, param := range constructor.Parameters {
-			if paramIdx < len(args) {
-				constructorEnv.Set(param.Value, args[paramIdx])
+// construct ejecuta sobre la instancia la sobrecarga de crear cuya cantidad
+// de parámetros coincide con los argumentos, buscándola en la clase y luego
+// en sus padres. Sin ningún crear en la cadena solo se aceptan cero
+// argumentos.
+func construct(instance *object.Instance, class *object.Class, args []object.Object) object.Object {
+	declared := false
+	for c := class; c != nil; c = c.Parent {
+		for _, constructor := range c.Constructors {
+			declared = true
+			if len(constructor.Parameters) != len(args) {
+				continue
 			}
+			result := applyFunction(bindMethod(instance, constructor), args)
+			if isError(result) {
+				return result
+			}
+			return NULL
 		}
+	}
+
+	if !declared && len(args) == 0 {
+		return NULL
+	}
+	return newError("%s no tiene un constructor crear con %d argumento(s)%s", class.Name, len(args), constructorArities(class))
+}
 
-		// Ejecutar el constructor
-		Eval(constructor.Body, constructorEnv)
+// hasConstructor indica si la clase o alguno de sus padres define crear
+func hasConstructor(class *object.Class) bool {
+	for c := class; c != nil; c = c.Parent {
+		if len(c.Constructors) > 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	return instance
+// constructorArities describe las sobrecargas disponibles para los mensajes de error
+func constructorArities(class *object.Class) string {
+	arities := []string{}
+	for c := class; c != nil; c = c.Parent {
+		for _, constructor := range c.Constructors {
+			arities = append(arities, fmt.Sprintf("%d", len(constructor.Parameters)))
+		}
+	}
+	if len(arities) == 0 {
+		return ""
+	}
+	return ": acepta " + strings.Join(arities, " o ")
 }
 
 // Funciones auxiliares
@@ -717,6 +3218,10 @@ func isTruthy(obj object.Object) bool {
 			return obj.(*object.Integer).Value != 0
 		case object.FLOAT_OBJ:
 			return obj.(*object.Float).Value != 0
+		case object.DECIMAL_OBJ:
+			return !obj.(*object.Decimal).IsZero()
+		case object.RATIONAL_OBJ:
+			return obj.(*object.Rational).Value.Sign() != 0
 		case object.STRING_OBJ:
 			return obj.(*object.String).Value != ""
 		default:
@@ -725,6 +3230,59 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
+// floorRat redondea una fracción hacia abajo. El denominador de big.Rat es
+// siempre positivo, así que la división euclídea coincide con el piso.
+func floorRat(value *big.Rat) *big.Int {
+	return new(big.Int).Div(value.Num(), value.Denom())
+}
+
+// toRational convierte un número exacto (ENTERO, DECIMAL_EXACTO o FRACCION) a fracción
+func toRational(obj object.Object) (*object.Rational, bool) {
+	switch obj := obj.(type) {
+	case *object.Rational:
+		return obj, true
+	case *object.Integer:
+		return object.NewRationalFromInt(obj.Value), true
+	case *object.Decimal:
+		return &object.Rational{Value: obj.Rat()}, true
+	default:
+		return nil, false
+	}
+}
+
+// exactRat devuelve el valor exacto de cualquier número como fracción; falla
+// con NaN e infinito, que no tienen uno
+func exactRat(obj object.Object) (*big.Rat, bool) {
+	switch obj := obj.(type) {
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(obj.Value), true
+	default:
+		rational, ok := toRational(obj)
+		if !ok {
+			return nil, false
+		}
+		return rational.Value, true
+	}
+}
+
+// toFloat convierte cualquier número a DECIMAL de coma flotante
+func toFloat(obj object.Object) (*object.Float, bool) {
+	switch obj := obj.(type) {
+	case *object.Float:
+		return obj, true
+	case *object.Integer:
+		return &object.Float{Value: float64(obj.Value)}, true
+	case *object.Rational:
+		value, _ := obj.Value.Float64()
+		return &object.Float{Value: value}, true
+	default:
+		return nil, false
+	}
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -746,14 +3304,40 @@ var builtins = map[string]*object.Builtin{
 
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Set:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.Tuple:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Range:
+				return RangeLength(arg)
+			case *object.Enum:
+				return &object.Integer{Value: int64(len(arg.Members))}
 			default:
 				return newError("argumento para 'longitud' no soportado, se obtuvo %s", args[0].Type())
 			}
 		},
 	},
+	"indices_estrictos": {
+		// Activa o desactiva los errores por índice fuera de rango; devuelve
+		// el modo anterior
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("número incorrecto de argumentos para 'indices_estrictos': se esperaba 1, se obtuvo %d", len(args))
+			}
+
+			enabled, ok := args[0].(*object.Boolean)
+			if !ok {
+				return newError("argumento para 'indices_estrictos' debe ser BOOLEANO, se obtuvo %s", args[0].Type())
+			}
+
+			previous := strictIndexing
+			strictIndexing = enabled.Value
+			return nativeBoolToBooleanObject(previous)
+		},
+	},
 	"mostrar": {
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
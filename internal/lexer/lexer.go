@@ -1,7 +1,6 @@
 package lexer
 
 import (
-	"strings"
 	"unicode"
 )
 
@@ -49,6 +48,14 @@ func (l *Lexer) peekChar() byte {
 	return l.input[l.readPosition]
 }
 
+// peekSecondChar devuelve el carácter que sigue a peekChar sin avanzar
+func (l *Lexer) peekSecondChar() byte {
+	if l.readPosition+1 >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition+1]
+}
+
 // NextToken lee el siguiente token desde el texto de entrada
 func (l *Lexer) NextToken() Token {
 	var tok Token
@@ -56,8 +63,9 @@ func (l *Lexer) NextToken() Token {
 	l.skipWhitespace()
 
 	// Almacenar posición para el token actual
-	tok.Line = l.line
-	tok.Column = l.column
+	line, column := l.line, l.column
+	tok.Line = line
+	tok.Column = column
 
 	switch l.ch {
 	case '=':
@@ -123,6 +131,23 @@ func (l *Lexer) NextToken() Token {
 		} else {
 			tok = newToken(POWER, l.ch)
 		}
+	case '?':
+		switch l.peekChar() {
+		case '.':
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: QUESTION_DOT, Literal: string(ch) + string(l.ch)}
+		case '[':
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: QUESTION_BRACKET, Literal: string(ch) + string(l.ch)}
+		case '?':
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: NULL_COALESCE, Literal: string(ch) + string(l.ch)}
+		default:
+			tok = newToken(ILLEGAL, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -132,9 +157,40 @@ func (l *Lexer) NextToken() Token {
 			tok = newToken(BANG, l.ch)
 		}
 	case '<':
-		tok = newToken(LT, l.ch)
+		if l.peekChar() == '<' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: SHIFT_LEFT, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: LT_EQ, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(LT, l.ch)
+		}
 	case '>':
-		tok = newToken(GT, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: SHIFT_RIGHT, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: GT_EQ, Literal: string(ch) + string(l.ch)}
+		} else {
+			tok = newToken(GT, l.ch)
+		}
+	case '&':
+		tok = newToken(BIT_AND, l.ch)
+	case '|':
+		if l.peekChar() == '>' {
+			l.readChar()
+			tok = Token{Type: PIPE, Literal: "|>"}
+		} else {
+			tok = newToken(BIT_OR, l.ch)
+		}
+	case '~':
+		tok = newToken(TILDE, l.ch)
 	case ':':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -148,7 +204,20 @@ func (l *Lexer) NextToken() Token {
 	case ';':
 		tok = newToken(SEMICOLON, l.ch)
 	case '.':
-		tok = newToken(DOT, l.ch)
+		if l.peekChar() == '.' && l.peekSecondChar() == '.' {
+			l.readChar()
+			l.readChar()
+			tok = Token{Type: ELLIPSIS, Literal: "..."}
+		} else if l.peekChar() == '.' && l.peekSecondChar() == '=' {
+			l.readChar()
+			l.readChar()
+			tok = Token{Type: RANGE_INCLUSIVE, Literal: "..="}
+		} else if l.peekChar() == '.' {
+			l.readChar()
+			tok = Token{Type: RANGE, Literal: ".."}
+		} else {
+			tok = newToken(DOT, l.ch)
+		}
 	case '(':
 		tok = newToken(LPAREN, l.ch)
 	case ')':
@@ -168,7 +237,12 @@ func (l *Lexer) NextToken() Token {
 		tok.Literal = ""
 		tok.Type = EOF
 	default:
-		if isLetter(l.ch) {
+		if l.ch == 'd' && (l.peekChar() == '"' || l.peekChar() == '\'') {
+			// Literal de decimal exacto: d"19.99"
+			l.readChar()
+			tok.Type = DECIMAL
+			tok.Literal = l.readString(l.ch)
+		} else if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = LookupIdent(tok.Literal)
 			return tok
@@ -181,6 +255,10 @@ func (l *Lexer) NextToken() Token {
 		}
 	}
 
+	// newToken y los operadores de dos caracteres crean el token de cero
+	tok.Line = line
+	tok.Column = column
+
 	l.readChar()
 	return tok
 }
@@ -228,7 +306,8 @@ func (l *Lexer) readNumber() string {
 	position := l.position
 	hasDot := false
 
-	for isDigit(l.ch) || (l.ch == '.' && !hasDot) {
+	// El punto solo es decimal si le sigue un dígito; así 1..5 es un rango
+	for isDigit(l.ch) || (l.ch == '.' && !hasDot && isDigit(l.peekChar())) {
 		if l.ch == '.' {
 			hasDot = true
 		}
@@ -17,9 +17,10 @@ const (
 	EOF     = "EOF"     // Fin de archivo
 
 	// Identificadores y literales
-	IDENT  = "IDENT"  // identificadores: x, y, foo, etc.
-	NUM    = "NUM"    // números: 1343456, 1.34, etc.
-	STRING = "STRING" // cadenas: "foo", "bar", etc.
+	IDENT   = "IDENT"   // identificadores: x, y, foo, etc.
+	NUM     = "NUM"     // números: 1343456, 1.34, etc.
+	STRING  = "STRING"  // cadenas: "foo", "bar", etc.
+	DECIMAL = "DECIMAL" // decimales exactos: d"19.99"
 
 	// Operadores
 	ASSIGN   = "="
@@ -31,8 +32,17 @@ const (
 	MOD      = "%"
 	POWER    = "^"
 
+	// Operadores a nivel de bits y división entera
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	TILDE       = "~" // negación de bits como prefijo, o exclusivo como infijo
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
+
 	LT     = "<"
 	GT     = ">"
+	LT_EQ  = "<="
+	GT_EQ  = ">="
 	EQ     = "=="
 	NOT_EQ = "!="
 
@@ -45,11 +55,24 @@ const (
 	POW_ASSIGN   = "^="
 	DECLARE      = ":="
 
+	// Navegación segura ante nulo
+	QUESTION_DOT     = "?."
+	QUESTION_BRACKET = "?["
+	NULL_COALESCE    = "??"
+
+	// Tubería: x |> f(a) equivale a f(x, a)
+	PIPE = "|>"
+
 	// Delimitadores
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
 	DOT       = "."
+	ELLIPSIS  = "..." // expansión de iterables: [...a, ...b], f(...args)
+
+	// Rangos de enteros
+	RANGE           = ".."  // sin incluir el final: 1..5 es 1, 2, 3, 4
+	RANGE_INCLUSIVE = "..=" // incluyendo el final: 1..=5 es 1, 2, 3, 4, 5
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -61,6 +84,13 @@ const (
 	// Palabras clave
 	FUNCTION  = "FUNCTION"
 	CLASS     = "CLASS"
+	ENUM      = "ENUM"
+	ABSTRACT  = "ABSTRACT"
+	TRAIT     = "TRAIT"
+	USES      = "USES"
+	IMPORT    = "IMPORT"
+	EXPORT    = "EXPORT"
+	AS        = "AS"
 	PROTO     = "PROTO"
 	IF        = "IF"
 	ELSE      = "ELSE"
@@ -101,12 +131,21 @@ const (
 	CATCH     = "CATCH"
 	FINALLY   = "FINALLY"
 	THROW     = "THROW"
+	INT_DIV   = "INT_DIV"
+	YIELD     = "YIELD"
 )
 
 // Mapeo de palabras clave a tipos de tokens
 var keywords = map[string]TokenType{
 	"fun":        FUNCTION,
 	"clase":      CLASS,
+	"enum":       ENUM,
+	"abstracta":  ABSTRACT,
+	"rasgo":      TRAIT,
+	"usa":        USES,
+	"importar":   IMPORT,
+	"exportar":   EXPORT,
+	"como":       AS,
 	"proto":      PROTO,
 	"si":         IF,
 	"sino":       ELSE,
@@ -147,6 +186,8 @@ var keywords = map[string]TokenType{
 	"atrapar":    CATCH,
 	"finalmente": FINALLY,
 	"lanzar":     THROW,
+	"div":        INT_DIV,
+	"producir":   YIELD,
 }
 
 // LookupIdent revisa si un identificador es una palabra clave.
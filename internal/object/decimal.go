@@ -0,0 +1,265 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RoundingMode es el modo de redondeo usado por los decimales exactos
+type RoundingMode int
+
+// Modos de redondeo soportados
+const (
+	RoundHalfEven RoundingMode = iota // mitad al par (redondeo bancario)
+	RoundHalfUp                       // mitad hacia arriba (alejándose de cero)
+)
+
+// Nombres de los modos de redondeo tal como se usan desde Gaby
+var RoundingModeNames = map[string]RoundingMode{
+	"mitad_par":    RoundHalfEven,
+	"mitad_arriba": RoundHalfUp,
+}
+
+// Configuración global de los decimales exactos
+var (
+	// DecimalDivisionScale es la cantidad mínima de decimales que conserva una división
+	DecimalDivisionScale int32 = 10
+	// DecimalRounding es el modo de redondeo por defecto
+	DecimalRounding = RoundHalfEven
+)
+
+var bigTen = big.NewInt(10)
+
+// Decimal representa un número decimal exacto: Coef * 10^-Scale
+type Decimal struct {
+	Coef  *big.Int
+	Scale int32
+}
+
+func (d *Decimal) Type() ObjectType { return DECIMAL_OBJ }
+func (d *Decimal) Inspect() string  { return d.String() }
+
+// String devuelve la representación del decimal conservando su escala
+func (d *Decimal) String() string {
+	digits := new(big.Int).Abs(d.Coef).String()
+	sign := ""
+	if d.Coef.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.Scale == 0 {
+		return sign + digits
+	}
+
+	scale := int(d.Scale)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	point := len(digits) - scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// HashKey permite usar decimales como claves; 1.0 y 1.00 producen la misma
+// clave, que además coincide con la del entero 1 y d"1.5" con fraccion(3, 2)
+func (d *Decimal) HashKey() HashKey {
+	return ratHashKey(d.Rat())
+}
+
+// ParseDecimal convierte un texto como "-19.99" en un decimal exacto
+func ParseDecimal(s string) (*Decimal, error) {
+	text := strings.TrimSpace(s)
+	digits := strings.TrimLeft(text, "+-")
+	if len(text)-len(digits) > 1 {
+		return nil, fmt.Errorf("decimal no válido: %q", s)
+	}
+
+	intPart, fracPart, hasPoint := strings.Cut(digits, ".")
+	if intPart == "" && fracPart == "" {
+		return nil, fmt.Errorf("decimal no válido: %q", s)
+	}
+	if hasPoint && fracPart == "" {
+		return nil, fmt.Errorf("decimal no válido: %q", s)
+	}
+
+	for _, ch := range intPart + fracPart {
+		if ch < '0' || ch > '9' {
+			return nil, fmt.Errorf("decimal no válido: %q", s)
+		}
+	}
+
+	coef, _ := new(big.Int).SetString(intPart+fracPart, 10)
+	if strings.HasPrefix(text, "-") {
+		coef.Neg(coef)
+	}
+
+	return &Decimal{Coef: coef, Scale: int32(len(fracPart))}, nil
+}
+
+// NewDecimalFromInt crea un decimal con escala cero a partir de un entero
+func NewDecimalFromInt(value int64) *Decimal {
+	return &Decimal{Coef: big.NewInt(value), Scale: 0}
+}
+
+// IsZero indica si el decimal vale cero
+func (d *Decimal) IsZero() bool {
+	return d.Coef.Sign() == 0
+}
+
+// Neg devuelve el decimal con el signo invertido
+func (d *Decimal) Neg() *Decimal {
+	return &Decimal{Coef: new(big.Int).Neg(d.Coef), Scale: d.Scale}
+}
+
+// Abs devuelve el valor absoluto del decimal
+func (d *Decimal) Abs() *Decimal {
+	return &Decimal{Coef: new(big.Int).Abs(d.Coef), Scale: d.Scale}
+}
+
+// Add suma dos decimales; la escala del resultado es la mayor de ambas
+func (d *Decimal) Add(other *Decimal) *Decimal {
+	a, b, scale := alignDecimals(d, other)
+	return &Decimal{Coef: a.Add(a, b), Scale: scale}
+}
+
+// Sub resta dos decimales; la escala del resultado es la mayor de ambas
+func (d *Decimal) Sub(other *Decimal) *Decimal {
+	a, b, scale := alignDecimals(d, other)
+	return &Decimal{Coef: a.Sub(a, b), Scale: scale}
+}
+
+// Mul multiplica dos decimales; la escala del resultado es la suma de ambas
+func (d *Decimal) Mul(other *Decimal) *Decimal {
+	return &Decimal{
+		Coef:  new(big.Int).Mul(d.Coef, other.Coef),
+		Scale: d.Scale + other.Scale,
+	}
+}
+
+// Rem devuelve el resto de la división truncada; conserva el signo del dividendo.
+// Quien llama debe comprobar antes que el divisor no sea cero.
+func (d *Decimal) Rem(other *Decimal) *Decimal {
+	a, b, scale := alignDecimals(d, other)
+	return &Decimal{Coef: a.Rem(a, b), Scale: scale}
+}
+
+// Quo divide dos decimales redondeando a la escala indicada. Quien llama
+// debe comprobar antes que el divisor no sea cero.
+func (d *Decimal) Quo(other *Decimal, scale int32, mode RoundingMode) *Decimal {
+	// d / other = (d.Coef * 10^(scale - d.Scale + other.Scale)) / other.Coef * 10^-scale
+	num := new(big.Int).Set(d.Coef)
+	den := new(big.Int).Set(other.Coef)
+
+	shift := scale - d.Scale + other.Scale
+	if shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+
+	return &Decimal{Coef: roundQuo(num, den, mode), Scale: scale}
+}
+
+// Round redondea el decimal a la escala indicada
+func (d *Decimal) Round(scale int32, mode RoundingMode) *Decimal {
+	if scale >= d.Scale {
+		return &Decimal{
+			Coef:  new(big.Int).Mul(d.Coef, pow10(scale-d.Scale)),
+			Scale: scale,
+		}
+	}
+
+	return &Decimal{Coef: roundQuo(d.Coef, pow10(d.Scale-scale), mode), Scale: scale}
+}
+
+// Normalize elimina los ceros sobrantes a la derecha del punto decimal
+func (d *Decimal) Normalize() *Decimal {
+	return d.TrimTo(0)
+}
+
+// TrimTo elimina ceros a la derecha sin bajar de la escala mínima indicada
+func (d *Decimal) TrimTo(minScale int32) *Decimal {
+	coef := new(big.Int).Set(d.Coef)
+	scale := d.Scale
+	rem := new(big.Int)
+
+	for scale > minScale {
+		q, r := new(big.Int).QuoRem(coef, bigTen, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		coef = q
+		scale--
+	}
+
+	return &Decimal{Coef: coef, Scale: scale}
+}
+
+// Cmp compara dos decimales y devuelve -1, 0 o 1
+func (d *Decimal) Cmp(other *Decimal) int {
+	a, b, _ := alignDecimals(d, other)
+	return a.Cmp(b)
+}
+
+// Rat devuelve el valor exacto del decimal como fracción
+func (d *Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.Coef, pow10(d.Scale))
+}
+
+// alignDecimals devuelve los coeficientes de ambos decimales llevados a la misma escala
+func alignDecimals(a, b *Decimal) (*big.Int, *big.Int, int32) {
+	x := new(big.Int).Set(a.Coef)
+	y := new(big.Int).Set(b.Coef)
+
+	switch {
+	case a.Scale > b.Scale:
+		y.Mul(y, pow10(a.Scale-b.Scale))
+		return x, y, a.Scale
+	case b.Scale > a.Scale:
+		x.Mul(x, pow10(b.Scale-a.Scale))
+		return x, y, b.Scale
+	default:
+		return x, y, a.Scale
+	}
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// roundQuo calcula num/den redondeado a un entero según el modo indicado
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	// Comparar el doble del resto con el divisor para saber si pasamos de la mitad
+	twiceRem := new(big.Int).Abs(r)
+	twiceRem.Lsh(twiceRem, 1)
+	half := twiceRem.Cmp(new(big.Int).Abs(den))
+
+	roundAway := false
+	switch {
+	case half > 0:
+		roundAway = true
+	case half == 0:
+		if mode == RoundHalfUp {
+			roundAway = true
+		} else {
+			roundAway = q.Bit(0) == 1
+		}
+	}
+
+	if roundAway {
+		// El cociente se aleja de cero en la dirección del signo del resultado
+		if (num.Sign() < 0) != (den.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	return q
+}
@@ -0,0 +1,59 @@
+package object
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strings"
+)
+
+// Enum representa una enumeración: un conjunto cerrado de valores con nombre
+// declarados con 'enum Color { Rojo, Verde, Azul }'
+type Enum struct {
+	Name    string
+	Members []*EnumMember // en orden de declaración
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, member := range e.Members {
+		names = append(names, member.Name)
+	}
+
+	out.WriteString("enum ")
+	out.WriteString(e.Name)
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// Member devuelve el valor con el nombre indicado
+func (e *Enum) Member(name string) (*EnumMember, bool) {
+	for _, member := range e.Members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return nil, false
+}
+
+// EnumMember es uno de los valores de una enumeración. Cada valor existe una
+// sola vez, así que dos valores son iguales solo si son el mismo objeto.
+type EnumMember struct {
+	Enum    *Enum
+	Name    string
+	Ordinal int64 // posición en la declaración, desde 0
+}
+
+func (m *EnumMember) Type() ObjectType { return ENUM_MEMBER_OBJ }
+func (m *EnumMember) Inspect() string  { return m.Enum.Name + "." + m.Name }
+
+func (m *EnumMember) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(m.Inspect()))
+	return HashKey{Type: m.Type(), Value: h.Sum64()}
+}
@@ -0,0 +1,81 @@
+package object
+
+// Iterator es un iterador incorporado. Recorre una secuencia a partir de una
+// función que devuelve el siguiente elemento, o false cuando no quedan más, y
+// guarda un elemento por adelantado para poder responder a tiene_siguiente().
+type Iterator struct {
+	next     func() (Object, bool)
+	close    func()
+	buffered Object
+	hasValue bool
+	done     bool
+}
+
+// NewIterator crea un iterador a partir de la función que produce los elementos
+func NewIterator(next func() (Object, bool)) *Iterator {
+	return &Iterator{next: next}
+}
+
+// NewClosableIterator crea un iterador que además libera recursos (como la
+// gorrutina de un generador) al cerrarse
+func NewClosableIterator(next func() (Object, bool), close func()) *Iterator {
+	return &Iterator{next: next, close: close}
+}
+
+// NewSliceIterator crea un iterador que recorre los elementos dados en orden
+func NewSliceIterator(elements []Object) *Iterator {
+	i := 0
+	return NewIterator(func() (Object, bool) {
+		if i >= len(elements) {
+			return nil, false
+		}
+		i++
+		return elements[i-1], true
+	})
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string  { return "iterador" }
+
+// HasNext indica si quedan elementos por recorrer
+func (it *Iterator) HasNext() bool {
+	if it.hasValue {
+		return true
+	}
+	if it.done {
+		return false
+	}
+
+	value, ok := it.next()
+	if !ok {
+		it.done = true
+		return false
+	}
+
+	it.buffered = value
+	it.hasValue = true
+	return true
+}
+
+// Close termina el recorrido; los elementos pendientes se descartan
+func (it *Iterator) Close() {
+	it.done = true
+	it.buffered = nil
+	it.hasValue = false
+	if it.close != nil {
+		it.close()
+		it.close = nil
+	}
+}
+
+// Next devuelve el siguiente elemento, o false si no quedan más
+func (it *Iterator) Next() (Object, bool) {
+	if !it.HasNext() {
+		return nil, false
+	}
+
+	value := it.buffered
+	it.buffered = nil
+	it.hasValue = false
+	return value, true
+}
@@ -0,0 +1,27 @@
+package object
+
+import "fmt"
+
+// Module es un archivo .gaby cargado con 'importar'. Se evalúa una sola vez
+// en su propio entorno y solo expone sus nombres públicos.
+type Module struct {
+	Name    string
+	Path    string // ruta absoluta, también clave de la caché de módulos
+	Env     *Environment
+	Exports []string // nombres públicos en orden alfabético
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("módulo %s (%s)", m.Name, m.Path)
+}
+
+// Export devuelve el valor actual de un nombre público del módulo
+func (m *Module) Export(name string) (Object, bool) {
+	for _, export := range m.Exports {
+		if export == name {
+			return m.Env.Get(name)
+		}
+	}
+	return nil, false
+}
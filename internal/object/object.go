@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/umdis/gaby-interpreter/internal/parser"
@@ -16,6 +19,8 @@ type ObjectType string
 const (
 	INTEGER_OBJ      = "ENTERO"
 	FLOAT_OBJ        = "DECIMAL"
+	DECIMAL_OBJ      = "DECIMAL_EXACTO"
+	RATIONAL_OBJ     = "FRACCION"
 	BOOLEAN_OBJ      = "BOOLEANO"
 	NULL_OBJ         = "NULO"
 	RETURN_VALUE_OBJ = "RETORNO"
@@ -25,6 +30,15 @@ const (
 	BUILTIN_OBJ      = "INCORPORADO"
 	ARRAY_OBJ        = "LISTA"
 	HASH_OBJ         = "MAPA"
+	SET_OBJ          = "CONJUNTO"
+	TUPLE_OBJ        = "TUPLA"
+	ITERATOR_OBJ     = "ITERADOR"
+	RANGE_OBJ        = "RANGO"
+	ENUM_OBJ         = "ENUM"
+	ENUM_MEMBER_OBJ  = "VALOR_ENUM"
+	TRAIT_OBJ        = "RASGO"
+	ENVIRONMENT_OBJ  = "ENTORNO"
+	MODULE_OBJ       = "MODULO"
 	CLASS_OBJ        = "CLASE"
 	INSTANCE_OBJ     = "INSTANCIA"
 )
@@ -88,10 +102,11 @@ func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
 // Function representa un objeto función
 type Function struct {
-	Parameters []*parser.Identifier
-	Body       *parser.BlockStatement
-	Env        *Environment
-	Name       string
+	Parameters  []*parser.Identifier
+	Body        *parser.BlockStatement
+	Env         *Environment
+	Name        string
+	IsGenerator bool // contiene 'producir': llamarla devuelve un generador
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -156,7 +171,34 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
-// HashKey es el tipo para las claves de mapas
+// Tuple representa una secuencia inmutable de valores; se puede usar como
+// clave compuesta en mapas y conjuntos
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE_OBJ }
+func (t *Tuple) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range t.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(t.Elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// HashKey es el tipo para las claves de mapas. Los tipos que no pueden
+// calcularla por sí solos (tuplas, instancias con hash()) la obtienen desde
+// el evaluador.
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -182,6 +224,19 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// Un decimal comparte clave con el número exacto de igual valor, así 1 y 1.0
+// son la misma clave igual que 1 == 1.0, y 1.5 la misma que fraccion(3, 2).
+// Todo decimal finito es una fracción exacta; solo NaN e infinito no lo son.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+	if math.IsNaN(f.Value) || math.IsInf(f.Value, 0) {
+		return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+	}
+	return ratHashKey(new(big.Rat).SetFloat64(f.Value))
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -194,9 +249,55 @@ type HashPair struct {
 	Value Object
 }
 
-// Hash representa un objeto mapa/diccionario
+// Hash representa un objeto mapa/diccionario. Las búsquedas usan Pairs y Keys
+// conserva el orden de inserción para iterar e imprimir de forma determinista.
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+}
+
+// NewHash crea un mapa vacío
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Len devuelve la cantidad de pares del mapa
+func (h *Hash) Len() int {
+	return len(h.Keys)
+}
+
+// Get devuelve el par guardado en la posición indicada
+func (h *Hash) Get(slot HashKey) (HashPair, bool) {
+	pair, ok := h.Pairs[slot]
+	return pair, ok
+}
+
+// Put guarda un par en la posición indicada; una clave que ya existía
+// conserva su lugar en el orden de inserción
+func (h *Hash) Put(slot HashKey, pair HashPair) {
+	if _, ok := h.Pairs[slot]; !ok {
+		h.Keys = append(h.Keys, slot)
+	}
+	h.Pairs[slot] = pair
+}
+
+// Copy devuelve un mapa nuevo con los mismos pares y el mismo orden
+func (h *Hash) Copy() *Hash {
+	result := NewHash()
+	for _, key := range h.Keys {
+		result.Pairs[key] = h.Pairs[key]
+		result.Keys = append(result.Keys, key)
+	}
+	return result
+}
+
+// Items devuelve los pares en orden de inserción
+func (h *Hash) Items() []HashPair {
+	items := make([]HashPair, 0, len(h.Keys))
+	for _, key := range h.Keys {
+		items = append(items, h.Pairs[key])
+	}
+	return items
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -204,7 +305,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Items() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -219,20 +320,33 @@ func (h *Hash) Inspect() string {
 // Class representa un objeto clase
 type Class struct {
 	Name       string
-	Properties map[string]Object
+	Fields     []*parser.LetStatement // 'guarda' del cuerpo, evaluados en cada instancia
+	Env        *Environment           // entorno donde se definió la clase
 	Methods    map[string]*Function
+	// Sobrecargas de crear, distinguidas por su cantidad de parámetros
+	Constructors []*Function
+	Getters    map[string]*Function // obtener nombre() { ... }
+	Setters    map[string]*Function // asignar nombre(valor) { ... }
 	Parent     *Class
+	// Una clase abstracta no se puede instanciar; sus métodos abstractos
+	// deben implementarlos las subclases concretas
+	Abstract        bool
+	AbstractMethods []string
+	Traits          []*Trait // rasgos incluidos con 'usa'
 }
 
 func (c *Class) Type() ObjectType { return CLASS_OBJ }
 func (c *Class) Inspect() string {
 	var out bytes.Buffer
 
+	if c.Abstract {
+		out.WriteString("abstracta ")
+	}
 	out.WriteString("clase ")
 	out.WriteString(c.Name)
 
 	if c.Parent != nil {
-		out.WriteString(" hereda ")
+		out.WriteString(" extiende ")
 		out.WriteString(c.Parent.Name)
 	}
 
@@ -248,8 +362,17 @@ type Instance struct {
 	Env        *Environment
 }
 
+// InstanceText, si está definida, devuelve el texto de una instancia y si
+// pudo obtenerlo. El evaluador la usa para llamar a a_texto() de la clase.
+var InstanceText func(*Instance) (string, bool)
+
 func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
 func (i *Instance) Inspect() string {
+	if InstanceText != nil {
+		if text, ok := InstanceText(i); ok {
+			return text
+		}
+	}
 	return fmt.Sprintf("instancia de %s", i.Class.Name)
 }
 
@@ -259,6 +382,52 @@ type Environment struct {
 	outer *Environment
 }
 
+// EnvironmentValue expone un entorno a los programas como valor de primera
+// clase, para ejecutar código en un ámbito aislado con ejecutar_codigo
+type EnvironmentValue struct {
+	Env *Environment
+}
+
+func (ev *EnvironmentValue) Type() ObjectType { return ENVIRONMENT_OBJ }
+func (ev *EnvironmentValue) Inspect() string {
+	return fmt.Sprintf("entorno(%s)", strings.Join(ev.Env.Names(), ", "))
+}
+
+// Names devuelve en orden alfabético los nombres definidos en el propio
+// entorno, sin los de los entornos exteriores
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Bindings devuelve las variables visibles desde el entorno sin contar las de
+// stop ni las de sus exteriores; si un nombre está en varios, gana el más
+// cercano
+func (e *Environment) Bindings(stop *Environment) map[string]Object {
+	bindings := map[string]Object{}
+	for env := e; env != nil && env != stop; env = env.outer {
+		for name, value := range env.store {
+			if _, shadowed := bindings[name]; !shadowed {
+				bindings[name] = value
+			}
+		}
+	}
+	return bindings
+}
+
+// Root devuelve el entorno más exterior de la cadena
+func (e *Environment) Root() *Environment {
+	root := e
+	for root.outer != nil {
+		root = root.outer
+	}
+	return root
+}
+
 // NewEnvironment crea un nuevo entorno
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
@@ -285,4 +454,21 @@ func (e *Environment) Get(name string) (Object, bool) {
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
+}
+
+// Delete quita una variable del propio entorno
+func (e *Environment) Delete(name string) {
+	delete(e.store, name)
+}
+
+// Assign actualiza la variable en el entorno donde fue definida; si no existe
+// en ninguno, la define en el entorno actual
+func (e *Environment) Assign(name string, val Object) Object {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return val
+		}
+	}
+	return e.Set(name, val)
 }
\ No newline at end of file
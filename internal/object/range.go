@@ -0,0 +1,139 @@
+package object
+
+import (
+	"fmt"
+	"math"
+)
+
+// Range representa una secuencia de enteros que se calcula bajo demanda:
+// Start, Start+Step, ... hasta Stop, que solo se incluye si Inclusive. No
+// guarda sus elementos, así que su tamaño en memoria no depende de la
+// cantidad de números. Las distancias se calculan sin signo para que los
+// rangos que llegan a los extremos de int64 no se desborden.
+type Range struct {
+	Start     int64
+	Stop      int64
+	Step      int64 // nunca cero
+	Inclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	operator := ".."
+	if r.Inclusive {
+		operator = "..="
+	}
+	if r.Step == 1 {
+		return fmt.Sprintf("%d%s%d", r.Start, operator, r.Stop)
+	}
+	return fmt.Sprintf("%d%s%d paso %d", r.Start, operator, r.Stop, r.Step)
+}
+
+// span devuelve la distancia de Start a Stop y el tamaño del paso, ambos sin
+// signo; forward es false si Stop queda detrás de Start según el paso
+func (r *Range) span() (dist, step uint64, forward bool) {
+	if r.Step > 0 {
+		if r.Stop < r.Start {
+			return 0, 0, false
+		}
+		return uint64(r.Stop) - uint64(r.Start), uint64(r.Step), true
+	}
+	if r.Stop > r.Start {
+		return 0, 0, false
+	}
+	// -Step se desborda con math.MinInt64, pero su valor sin signo es el correcto
+	return uint64(r.Start) - uint64(r.Stop), uint64(-r.Step), true
+}
+
+// lastOffset devuelve cuántos pasos hay del primer al último elemento; ok es
+// false si el rango está vacío
+func (r *Range) lastOffset() (steps uint64, ok bool) {
+	dist, step, forward := r.span()
+	if !forward {
+		return 0, false
+	}
+	if r.Inclusive {
+		return dist / step, true
+	}
+	if dist == 0 {
+		return 0, false
+	}
+	return (dist - 1) / step, true
+}
+
+// Len devuelve la cantidad de elementos del rango; fits es false si no cabe
+// en un ENTERO, algo que solo ocurre con rangos de casi todos los int64
+func (r *Range) Len() (length int64, fits bool) {
+	steps, ok := r.lastOffset()
+	if !ok {
+		return 0, true
+	}
+	if steps >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(steps) + 1, true
+}
+
+// Last devuelve el último elemento del rango; ok es false si está vacío
+func (r *Range) Last() (last int64, ok bool) {
+	steps, ok := r.lastOffset()
+	if !ok {
+		return 0, false
+	}
+	// Con aritmética modular el resultado es exacto aunque steps no quepa en
+	// un int64, porque el último elemento sí cabe
+	return r.Start + int64(steps)*r.Step, true
+}
+
+// At devuelve el elemento en la posición indicada; quien llama debe comprobar
+// antes que esté dentro del rango
+func (r *Range) At(index int64) int64 {
+	return r.Start + index*r.Step
+}
+
+// Index devuelve el elemento en la posición i; una posición negativa cuenta
+// desde el final (-1 es el último). ok es false si queda fuera del rango.
+func (r *Range) Index(i int64) (element int64, ok bool) {
+	length, fits := r.Len()
+	if !fits {
+		// Más elementos de los que puede señalar un índice: toda posición existe
+		if i >= 0 {
+			return r.At(i), true
+		}
+		last, _ := r.Last()
+		return last + (i+1)*r.Step, true
+	}
+	if i < 0 {
+		i += length
+	}
+	if i < 0 || i >= length {
+		return 0, false
+	}
+	return r.At(i), true
+}
+
+// Contains indica si el valor es uno de los elementos del rango
+func (r *Range) Contains(value int64) bool {
+	dist, step, forward := r.span()
+	if !forward {
+		return false
+	}
+
+	var offset uint64
+	if r.Step > 0 {
+		if value < r.Start {
+			return false
+		}
+		offset = uint64(value) - uint64(r.Start)
+	} else {
+		if value > r.Start {
+			return false
+		}
+		offset = uint64(r.Start) - uint64(value)
+	}
+
+	if offset > dist || (offset == dist && !r.Inclusive) {
+		return false
+	}
+	return offset%step == 0
+}
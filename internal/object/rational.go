@@ -0,0 +1,55 @@
+package object
+
+import (
+	"hash/fnv"
+	"math/big"
+)
+
+// Rational representa una fracción exacta, siempre reducida a su mínima expresión
+type Rational struct {
+	Value *big.Rat
+}
+
+func (r *Rational) Type() ObjectType { return RATIONAL_OBJ }
+func (r *Rational) Inspect() string {
+	if r.Value.IsInt() {
+		return r.Value.Num().String()
+	}
+	return r.Value.String()
+}
+
+// HashKey permite usar fracciones como claves; big.Rat ya está normalizado.
+func (r *Rational) HashKey() HashKey {
+	return ratHashKey(r.Value)
+}
+
+// ratHashKey es la clave que comparten todos los números exactos: un valor
+// entero usa la del ENTERO equivalente y uno fraccionario la de su fracción
+// reducida, así 1.5, d"1.5" y fraccion(3, 2) son la misma clave
+func ratHashKey(value *big.Rat) HashKey {
+	if value.IsInt() && value.Num().IsInt64() {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(value.Num().Int64())}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(value.String()))
+	return HashKey{Type: RATIONAL_OBJ, Value: h.Sum64()}
+}
+
+// NewRational crea una fracción a partir de numerador y denominador enteros.
+// Quien llama debe comprobar antes que el denominador no sea cero.
+func NewRational(num, den int64) *Rational {
+	return &Rational{Value: big.NewRat(num, den)}
+}
+
+// NewRationalFromInt crea una fracción con denominador uno
+func NewRationalFromInt(value int64) *Rational {
+	return &Rational{Value: new(big.Rat).SetInt64(value)}
+}
+
+// NewDecimalFromRat convierte una fracción a decimal exacto con la escala y el
+// modo de redondeo indicados
+func NewDecimalFromRat(r *big.Rat, scale int32, mode RoundingMode) *Decimal {
+	num := new(big.Int).Mul(r.Num(), pow10(scale))
+	return &Decimal{Coef: roundQuo(num, r.Denom(), mode), Scale: scale}
+}
@@ -0,0 +1,79 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Set representa un conjunto de elementos sin repetir. Los elementos se
+// guardan por su HashKey, que el evaluador calcula y resuelve en caso de
+// colisión, y se conserva el orden de inserción para que la iteración y la
+// impresión sean deterministas.
+type Set struct {
+	Elements map[HashKey]Object
+	Keys     []HashKey
+}
+
+// NewSet crea un conjunto vacío
+func NewSet() *Set {
+	return &Set{Elements: make(map[HashKey]Object)}
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	// {} es un mapa vacío, así que el conjunto vacío se muestra como conjunto()
+	if len(s.Keys) == 0 {
+		return "conjunto()"
+	}
+
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range s.Items() {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Len devuelve la cantidad de elementos del conjunto
+func (s *Set) Len() int {
+	return len(s.Keys)
+}
+
+// Items devuelve los elementos en orden de inserción
+func (s *Set) Items() []Object {
+	items := make([]Object, 0, len(s.Keys))
+	for _, key := range s.Keys {
+		items = append(items, s.Elements[key])
+	}
+	return items
+}
+
+// Get devuelve el elemento guardado en la posición indicada
+func (s *Set) Get(slot HashKey) (Object, bool) {
+	element, ok := s.Elements[slot]
+	return element, ok
+}
+
+// Add guarda un elemento en la posición indicada si estaba libre
+func (s *Set) Add(slot HashKey, element Object) {
+	if _, ok := s.Elements[slot]; ok {
+		return
+	}
+	s.Elements[slot] = element
+	s.Keys = append(s.Keys, slot)
+}
+
+// Copy devuelve un conjunto nuevo con los mismos elementos
+func (s *Set) Copy() *Set {
+	result := NewSet()
+	for _, key := range s.Keys {
+		result.Add(key, s.Elements[key])
+	}
+	return result
+}
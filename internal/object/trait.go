@@ -0,0 +1,39 @@
+package object
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Trait representa un rasgo: un grupo de métodos que varias clases pueden
+// incluir con 'usa', además de la herencia simple. Required son los métodos
+// que la clase que lo usa debe proporcionar.
+type Trait struct {
+	Name     string
+	Methods  map[string]*Function
+	Required []string
+}
+
+func (t *Trait) Type() ObjectType { return TRAIT_OBJ }
+func (t *Trait) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("rasgo ")
+	out.WriteString(t.Name)
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(t.MethodNames(), ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MethodNames devuelve los nombres de los métodos del rasgo en orden alfabético
+func (t *Trait) MethodNames() []string {
+	names := make([]string, 0, len(t.Methods))
+	for name := range t.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 
 	"github.com/umdis/gaby-interpreter/internal/lexer"
@@ -74,9 +75,10 @@ func (es *ExpressionStatement) String() string {
 
 // LetStatement representa una sentencia de asignación/declaración de variable (guarda)
 type LetStatement struct {
-	Token lexer.Token // token VAR
-	Name  *Identifier
-	Value Expression
+	Token   lexer.Token // token VAR
+	Name    *Identifier
+	Pattern *DestructuringPattern // guarda (a, b) = ...; en ese caso Name es nil
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
@@ -85,7 +87,11 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -97,6 +103,40 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// ImportStatement representa 'importar "ruta" como nombre' o
+// 'desde "ruta" importar a, b'
+type ImportStatement struct {
+	Token lexer.Token // token IMPORT o FROM
+	Path  string
+	Alias *Identifier   // nombre del módulo; nil en la forma 'desde'
+	Names []*Identifier // nombres traídos con 'desde'
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	if is.Alias == nil {
+		names := []string{}
+		for _, name := range is.Names {
+			names = append(names, name.String())
+		}
+		return fmt.Sprintf("desde %q importar %s", is.Path, strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("importar %q como %s", is.Path, is.Alias.String())
+}
+
+// ExportStatement marca como pública una declaración de un módulo
+// (exportar guarda x = 1, exportar fun f() { ... }, exportar clase C { ... })
+type ExportStatement struct {
+	Token     lexer.Token // token EXPORT
+	Statement Statement
+	Names     []string // nombres que define la declaración
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string       { return "exportar " + es.Statement.String() }
+
 // ReturnStatement representa una sentencia de retorno (devolver)
 type ReturnStatement struct {
 	Token       lexer.Token // token RETURN
@@ -159,6 +199,16 @@ func (fl *FloatLiteral) expressionNode()      {}
 func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
 
+// DecimalLiteral representa un literal de decimal exacto (d"19.99")
+type DecimalLiteral struct {
+	Token lexer.Token // token DECIMAL
+	Value string
+}
+
+func (dl *DecimalLiteral) expressionNode()      {}
+func (dl *DecimalLiteral) TokenLiteral() string { return dl.Token.Literal }
+func (dl *DecimalLiteral) String() string       { return "d\"" + dl.Value + "\"" }
+
 // StringLiteral representa un literal de cadena
 type StringLiteral struct {
 	Token lexer.Token // token STRING
@@ -230,6 +280,27 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression representa una asignación (x = 1, obj.campo = 2, lista[0] = 3)
+// o una declaración en el entorno actual (x := 1)
+type AssignExpression struct {
+	Token    lexer.Token // token = o :=
+	Target   Expression  // Identifier, DotExpression o IndexExpression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+
+	return out.String()
+}
+
 // IfExpression representa una expresión condicional (si/sino)
 type IfExpression struct {
 	Token       lexer.Token // token IF
@@ -256,6 +327,30 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// ConditionalExpression representa la forma en línea de si: valor si condición sino otro
+type ConditionalExpression struct {
+	Token       lexer.Token // token IF
+	Consequence Expression
+	Condition   Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" si ")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" sino ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // WhileExpression representa un bucle mientras
 type WhileExpression struct {
 	Token     lexer.Token // token WHILE
@@ -308,12 +403,51 @@ func (fe *ForExpression) String() string {
 	return out.String()
 }
 
+// YieldExpression representa 'producir valor' dentro de una función generadora
+type YieldExpression struct {
+	Token lexer.Token // token YIELD
+	Value Expression  // nil si no se produce ningún valor
+}
+
+func (ye *YieldExpression) expressionNode()      {}
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExpression) String() string {
+	if ye.Value == nil {
+		return "producir"
+	}
+	return "producir " + ye.Value.String()
+}
+
+// ForInExpression representa un bucle sobre un iterable (para x en lista)
+type ForInExpression struct {
+	Token    lexer.Token // token FOR
+	Target   Expression  // *Identifier o *DestructuringPattern
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fi *ForInExpression) expressionNode()      {}
+func (fi *ForInExpression) TokenLiteral() string { return fi.Token.Literal }
+func (fi *ForInExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("para ")
+	out.WriteString(fi.Target.String())
+	out.WriteString(" en ")
+	out.WriteString(fi.Iterable.String())
+	out.WriteString(" ")
+	out.WriteString(fi.Body.String())
+
+	return out.String()
+}
+
 // FunctionLiteral representa una definición de función (fun)
 type FunctionLiteral struct {
-	Token      lexer.Token // token FUNCTION
-	Parameters []*Identifier
-	Body       *BlockStatement
-	Name       string
+	Token       lexer.Token // token FUNCTION
+	Parameters  []*Identifier
+	Body        *BlockStatement
+	Name        string
+	IsGenerator bool // el cuerpo contiene 'producir'
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -363,11 +497,12 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
-// IndexExpression representa acceso a elementos de arrays (ej. array[0])
+// IndexExpression representa acceso a elementos de arrays (ej. array[0], array?[0])
 type IndexExpression struct {
-	Token lexer.Token // El token '['
-	Left  Expression
-	Index Expression
+	Token    lexer.Token // El token '[' o '?['
+	Left     Expression
+	Index    Expression
+	Optional bool // ?[ devuelve nulo si Left es nulo
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -377,6 +512,9 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -384,6 +522,43 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression representa una porción de una lista o texto (ej. lista[1:3], lista[::2])
+type SliceExpression struct {
+	Token    lexer.Token // El token '[' o '?['
+	Left     Expression
+	Start    Expression // nil si se omite
+	End      Expression // nil si se omite
+	Step     Expression // nil si se omite
+	Optional bool
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	part := func(exp Expression) string {
+		if exp == nil {
+			return ""
+		}
+		return exp.String()
+	}
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?")
+	}
+	out.WriteString("[")
+	out.WriteString(part(se.Start) + ":" + part(se.End))
+	if se.Step != nil {
+		out.WriteString(":" + se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
 // ArrayLiteral representa un literal de array (lista)
 type ArrayLiteral struct {
 	Token    lexer.Token // El token '['
@@ -411,6 +586,7 @@ func (al *ArrayLiteral) String() string {
 type HashLiteral struct {
 	Token lexer.Token // El token '{'
 	Pairs map[Expression]Expression
+	Keys  []Expression // Claves en el orden en que aparecen en el código
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -419,8 +595,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+": "+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -430,11 +606,154 @@ func (hl *HashLiteral) String() string {
 	return out.String()
 }
 
+// TupleLiteral representa un literal de tupla ((1, 2))
+type TupleLiteral struct {
+	Token    lexer.Token // El token '('
+	Elements []Expression
+}
+
+func (tl *TupleLiteral) expressionNode()      {}
+func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TupleLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(tl.Elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// SpreadExpression representa la expansión de un iterable (...lista) dentro
+// de un literal de colección o de los argumentos de una llamada
+type SpreadExpression struct {
+	Token lexer.Token // El token '...'
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// DestructuringPattern representa los nombres que reciben los elementos de un
+// iterable, como en guarda (a, b, ...resto) = lista
+type DestructuringPattern struct {
+	Token lexer.Token // El token '('
+	Names []*Identifier
+	Rest  *Identifier // nil si no hay ...resto
+}
+
+func (dp *DestructuringPattern) expressionNode()      {}
+func (dp *DestructuringPattern) TokenLiteral() string { return dp.Token.Literal }
+func (dp *DestructuringPattern) String() string {
+	names := []string{}
+	for _, name := range dp.Names {
+		names = append(names, name.String())
+	}
+	if dp.Rest != nil {
+		names = append(names, "..."+dp.Rest.String())
+	}
+
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+// SetLiteral representa un literal de conjunto ({1, 2, 3})
+type SetLiteral struct {
+	Token    lexer.Token // El token '{'
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// ComprehensionExpression representa una lista, conjunto o mapa por
+// comprensión: [x * 2 para x en lista si x > 0], {x para x en lista} o
+// {k: v para k, v en mapa}
+type ComprehensionExpression struct {
+	Token   lexer.Token // El token '[' o '{'
+	Key     Expression  // solo en mapas; nil en listas y conjuntos
+	Value   Expression
+	Clauses []*ComprehensionClause
+}
+
+func (ce *ComprehensionExpression) expressionNode()      {}
+func (ce *ComprehensionExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ComprehensionExpression) String() string {
+	var out bytes.Buffer
+
+	closing := "]"
+	if ce.Token.Type == lexer.LBRACE {
+		closing = "}"
+	}
+
+	out.WriteString(ce.Token.Literal)
+	if ce.Key != nil {
+		out.WriteString(ce.Key.String())
+		out.WriteString(": ")
+	}
+	out.WriteString(ce.Value.String())
+	for _, clause := range ce.Clauses {
+		out.WriteString(" ")
+		out.WriteString(clause.String())
+	}
+	out.WriteString(closing)
+
+	return out.String()
+}
+
+// ComprehensionClause es un 'para objetivo en iterable' de una comprensión
+// con los filtros 'si condición' que lo siguen. Las cláusulas posteriores se
+// anidan dentro de las anteriores.
+type ComprehensionClause struct {
+	Token      lexer.Token // El token 'para'
+	Target     Expression  // *Identifier o *DestructuringPattern
+	Iterable   Expression
+	Conditions []Expression
+}
+
+func (cc *ComprehensionClause) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("para ")
+	out.WriteString(cc.Target.String())
+	out.WriteString(" en ")
+	out.WriteString(cc.Iterable.String())
+	for _, condition := range cc.Conditions {
+		out.WriteString(" si ")
+		out.WriteString(condition.String())
+	}
+
+	return out.String()
+}
+
 // DotExpression representa una expresión de acceso a atributo mediante punto (objeto.atributo)
 type DotExpression struct {
-	Token    lexer.Token // El token '.'
+	Token    lexer.Token // El token '.' o '?.'
 	Object   Expression
 	Property *Identifier
+	Optional bool // ?. devuelve nulo si Object es nulo
 }
 
 func (de *DotExpression) expressionNode()      {}
@@ -443,6 +762,9 @@ func (de *DotExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(de.Object.String())
+	if de.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString(".")
 	out.WriteString(de.Property.String())
 
@@ -454,9 +776,15 @@ type ClassLiteral struct {
 	Token       lexer.Token // token CLASS
 	Name        *Identifier
 	Parent      *Identifier
+	Traits      []*Identifier // rasgos incluidos con 'usa'
 	Interfaces  []*Identifier
 	Properties  []*LetStatement
 	Methods     []*FunctionLiteral
+	Getters     []*FunctionLiteral // obtener nombre() { ... }
+	Setters     []*FunctionLiteral // asignar nombre(valor) { ... }
+	Abstract    bool
+	// Métodos abstractos: 'abstracta fun nombre(params)', sin cuerpo
+	AbstractMethods []*FunctionLiteral
 }
 
 func (cl *ClassLiteral) expressionNode()      {}
@@ -464,13 +792,25 @@ func (cl *ClassLiteral) TokenLiteral() string { return cl.Token.Literal }
 func (cl *ClassLiteral) String() string {
 	var out bytes.Buffer
 
+	if cl.Abstract {
+		out.WriteString("abstracta ")
+	}
 	out.WriteString("clase ")
 	out.WriteString(cl.Name.String())
 	
 	if cl.Parent != nil {
-		out.WriteString(" hereda ")
+		out.WriteString(" extiende ")
 		out.WriteString(cl.Parent.String())
 	}
+
+	if len(cl.Traits) > 0 {
+		traits := []string{}
+		for _, trait := range cl.Traits {
+			traits = append(traits, trait.String())
+		}
+		out.WriteString(" usa ")
+		out.WriteString(strings.Join(traits, ", "))
+	}
 	
 	if len(cl.Interfaces) > 0 {
 		out.WriteString(" implementa ")
@@ -487,6 +827,22 @@ func (cl *ClassLiteral) String() string {
 		out.WriteString("  " + prop.String() + "\n")
 	}
 	
+	for _, method := range cl.AbstractMethods {
+		params := []string{}
+		for _, param := range method.Parameters {
+			params = append(params, param.String())
+		}
+		out.WriteString("  abstracta fun " + method.Name + "(" + strings.Join(params, ", ") + ")\n")
+	}
+	
+	for _, getter := range cl.Getters {
+		out.WriteString("  obtener " + getter.String() + "\n")
+	}
+
+	for _, setter := range cl.Setters {
+		out.WriteString("  asignar " + setter.String() + "\n")
+	}
+	
 	for _, method := range cl.Methods {
 		out.WriteString("  " + method.String() + "\n")
 	}
@@ -496,6 +852,103 @@ func (cl *ClassLiteral) String() string {
 	return out.String()
 }
 
+// TraitLiteral representa la declaración de un rasgo
+// (rasgo Comparable { abstracta fun comparar(otro) fun menor(otro) { ... } })
+type TraitLiteral struct {
+	Token    lexer.Token // token TRAIT
+	Name     *Identifier
+	Methods  []*FunctionLiteral
+	Required []*FunctionLiteral // 'abstracta fun', sin cuerpo
+}
+
+func (tl *TraitLiteral) expressionNode()      {}
+func (tl *TraitLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TraitLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("rasgo ")
+	out.WriteString(tl.Name.String())
+	out.WriteString(" {\n")
+
+	for _, method := range tl.Required {
+		params := []string{}
+		for _, param := range method.Parameters {
+			params = append(params, param.String())
+		}
+		out.WriteString("  abstracta fun " + method.Name + "(" + strings.Join(params, ", ") + ")\n")
+	}
+
+	for _, method := range tl.Methods {
+		out.WriteString("  " + method.String() + "\n")
+	}
+	
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// EnumLiteral representa la declaración de una enumeración
+// (enum Color { Rojo, Verde, Azul })
+type EnumLiteral struct {
+	Token   lexer.Token // token ENUM
+	Name    *Identifier
+	Members []*Identifier
+}
+
+func (el *EnumLiteral) expressionNode()      {}
+func (el *EnumLiteral) TokenLiteral() string { return el.Token.Literal }
+func (el *EnumLiteral) String() string {
+	members := []string{}
+	for _, member := range el.Members {
+		members = append(members, member.String())
+	}
+
+	return "enum " + el.Name.String() + " { " + strings.Join(members, ", ") + " }"
+}
+
+// SwitchExpression representa 'evaluar valor { cuando a, b { ... } defecto { ... } }'
+type SwitchExpression struct {
+	Token   lexer.Token // token SWITCH
+	Subject Expression
+	Cases   []*SwitchCase
+	Default *BlockStatement // nil si no hay 'defecto'
+}
+
+func (se *SwitchExpression) expressionNode()      {}
+func (se *SwitchExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SwitchExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("evaluar ")
+	out.WriteString(se.Subject.String())
+	out.WriteString(" {\n")
+	for _, c := range se.Cases {
+		out.WriteString("  " + c.String() + "\n")
+	}
+	if se.Default != nil {
+		out.WriteString("  defecto " + se.Default.String() + "\n")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// SwitchCase es una rama 'cuando a, b { ... }' de evaluar
+type SwitchCase struct {
+	Token  lexer.Token // token WHEN
+	Values []Expression
+	Body   *BlockStatement
+}
+
+func (sc *SwitchCase) String() string {
+	values := []string{}
+	for _, v := range sc.Values {
+		values = append(values, v.String())
+	}
+
+	return "cuando " + strings.Join(values, ", ") + " " + sc.Body.String()
+}
+
 // NewExpression representa una creación de objeto mediante 'nuevo'
 type NewExpression struct {
 	Token     lexer.Token // token NEW
@@ -3,6 +3,8 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/umdis/gaby-interpreter/internal/lexer"
 )
@@ -12,9 +14,17 @@ const (
 	_ int = iota
 	LOWEST
 	ASSIGN      // =
+	TERNARY     // valor si condición sino otro
+	PIPE        // x |> f
+	COALESCE    // ??
 	LOGICAL     // y, o
 	EQUALS      // ==
-	LESSGREATER // > o <
+	LESSGREATER // > o <, y pertenencia con 'en'
+	RANGE       // a..b o a..=b
+	BITOR       // |
+	BITXOR      // ~
+	BITAND      // &
+	SHIFT       // << o >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X o !X
@@ -28,19 +38,44 @@ var precedences = map[lexer.TokenType]int{
 	lexer.ASSIGN:   ASSIGN,
 	lexer.EQ:       EQUALS,
 	lexer.NOT_EQ:   EQUALS,
+	lexer.IS:       EQUALS,
+	lexer.ISNOT:    EQUALS,
 	lexer.LT:       LESSGREATER,
 	lexer.GT:       LESSGREATER,
+	lexer.LT_EQ:    LESSGREATER,
+	lexer.GT_EQ:    LESSGREATER,
+	lexer.IN:       LESSGREATER,
 	lexer.PLUS:     SUM,
 	lexer.MINUS:    SUM,
 	lexer.SLASH:    PRODUCT,
 	lexer.ASTERISK: PRODUCT,
 	lexer.MOD:      PRODUCT,
 	lexer.POWER:    PRODUCT,
+	lexer.INT_DIV:  PRODUCT,
 	lexer.LPAREN:   CALL,
 	lexer.LBRACKET: INDEX,
 	lexer.DOT:      DOT,
 	lexer.AND:      LOGICAL,
 	lexer.OR:       LOGICAL,
+
+	// Asignación y navegación segura ante nulo
+	lexer.DECLARE:          ASSIGN,
+	lexer.IF:               TERNARY,
+	lexer.NULL_COALESCE:    COALESCE,
+	lexer.QUESTION_DOT:     DOT,
+	lexer.QUESTION_BRACKET: INDEX,
+	lexer.PIPE:             PIPE,
+
+	// Rangos, con menor precedencia que la aritmética: 0..n+1
+	lexer.RANGE:           RANGE,
+	lexer.RANGE_INCLUSIVE: RANGE,
+
+	// Operadores de bits, con menor precedencia que la aritmética
+	lexer.BIT_OR:      BITOR,
+	lexer.TILDE:       BITXOR,
+	lexer.BIT_AND:     BITAND,
+	lexer.SHIFT_LEFT:  SHIFT,
+	lexer.SHIFT_RIGHT: SHIFT,
 }
 
 // Tipo para funciones de prefijo
@@ -58,6 +93,10 @@ type Parser struct {
 
 	prefixParseFns map[lexer.TokenType]prefixParseFn
 	infixParseFns  map[lexer.TokenType]infixParseFn
+
+	// Funciones que se están analizando, la más interna al final; 'producir'
+	// marca como generadora a la última
+	functions []*FunctionLiteral
 }
 
 // New crea un nuevo Parser
@@ -70,13 +109,16 @@ func New(l *lexer.Lexer) *Parser {
 	// Registrar funciones para análisis de expresiones
 	p.prefixParseFns = make(map[lexer.TokenType]prefixParseFn)
 	p.registerPrefix(lexer.IDENT, p.parseIdentifier)
+	p.registerPrefix(lexer.THIS, p.parseIdentifier)
 	p.registerPrefix(lexer.NUM, p.parseNumberLiteral)
 	p.registerPrefix(lexer.STRING, p.parseStringLiteral)
+	p.registerPrefix(lexer.DECIMAL, p.parseDecimalLiteral)
 	p.registerPrefix(lexer.TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(lexer.FALSE, p.parseBooleanLiteral)
 	p.registerPrefix(lexer.NULL, p.parseNullLiteral)
 	p.registerPrefix(lexer.BANG, p.parsePrefixExpression)
 	p.registerPrefix(lexer.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(lexer.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(lexer.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(lexer.IF, p.parseIfExpression)
 	p.registerPrefix(lexer.FUNCTION, p.parseFunctionLiteral)
@@ -85,7 +127,13 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(lexer.WHILE, p.parseWhileExpression)
 	p.registerPrefix(lexer.FOR, p.parseForExpression)
 	p.registerPrefix(lexer.CLASS, p.parseClassLiteral)
+	p.registerPrefix(lexer.ABSTRACT, p.parseAbstractClass)
+	p.registerPrefix(lexer.TRAIT, p.parseTraitLiteral)
+	p.registerPrefix(lexer.ENUM, p.parseEnumLiteral)
+	p.registerPrefix(lexer.SWITCH, p.parseSwitchExpression)
 	p.registerPrefix(lexer.NEW, p.parseNewExpression)
+	p.registerPrefix(lexer.ELLIPSIS, p.parseSpreadExpression)
+	p.registerPrefix(lexer.YIELD, p.parseYieldExpression)
 
 	p.infixParseFns = make(map[lexer.TokenType]infixParseFn)
 	p.registerInfix(lexer.PLUS, p.parseInfixExpression)
@@ -96,13 +144,33 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(lexer.POWER, p.parseInfixExpression)
 	p.registerInfix(lexer.EQ, p.parseInfixExpression)
 	p.registerInfix(lexer.NOT_EQ, p.parseInfixExpression)
+	p.registerInfix(lexer.IS, p.parseInfixExpression)
+	p.registerInfix(lexer.ISNOT, p.parseInfixExpression)
 	p.registerInfix(lexer.LT, p.parseInfixExpression)
 	p.registerInfix(lexer.GT, p.parseInfixExpression)
+	p.registerInfix(lexer.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(lexer.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(lexer.IN, p.parseInfixExpression)
+	p.registerInfix(lexer.PIPE, p.parseInfixExpression)
+	p.registerInfix(lexer.RANGE, p.parseInfixExpression)
+	p.registerInfix(lexer.RANGE_INCLUSIVE, p.parseInfixExpression)
 	p.registerInfix(lexer.LPAREN, p.parseCallExpression)
 	p.registerInfix(lexer.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(lexer.DOT, p.parseDotExpression)
 	p.registerInfix(lexer.AND, p.parseInfixExpression)
 	p.registerInfix(lexer.OR, p.parseInfixExpression)
+	p.registerInfix(lexer.INT_DIV, p.parseInfixExpression)
+	p.registerInfix(lexer.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(lexer.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(lexer.TILDE, p.parseInfixExpression)
+	p.registerInfix(lexer.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(lexer.SHIFT_RIGHT, p.parseInfixExpression)
+	p.registerInfix(lexer.IF, p.parseConditionalExpression)
+	p.registerInfix(lexer.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(lexer.DECLARE, p.parseAssignExpression)
+	p.registerInfix(lexer.NULL_COALESCE, p.parseInfixExpression)
+	p.registerInfix(lexer.QUESTION_DOT, p.parseDotExpression)
+	p.registerInfix(lexer.QUESTION_BRACKET, p.parseIndexExpression)
 
 	// Leer los dos primeros tokens
 	p.nextToken()
@@ -178,6 +246,13 @@ func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
 }
 
 func (p *Parser) peekPrecedence() int {
+	// Un 'si' al comienzo de otra línea inicia una sentencia nueva y no
+	// un condicional en línea sobre la expresión anterior. Lo mismo con '~',
+	// que al comienzo de una línea es la negación de bits y no un xor.
+	if (p.peekTokenIs(lexer.IF) || p.peekTokenIs(lexer.TILDE)) && p.peekToken.Line != p.curToken.Line {
+		return LOWEST
+	}
+
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
@@ -198,6 +273,16 @@ func (p *Parser) parseStatement() Statement {
 		return p.parseLetStatement()
 	case lexer.RETURN:
 		return p.parseReturnStatement()
+	case lexer.IMPORT, lexer.FROM:
+		if stmt := p.parseImportStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case lexer.EXPORT:
+		if stmt := p.parseExportStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -206,11 +291,19 @@ func (p *Parser) parseStatement() Statement {
 func (p *Parser) parseLetStatement() *LetStatement {
 	stmt := &LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(lexer.IDENT) {
-		return nil
-	}
+	if p.peekTokenIs(lexer.LPAREN) {
+		p.nextToken()
+		stmt.Pattern = p.parseDestructuringPattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
 
-	stmt.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		stmt.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
 
 	if !p.expectPeek(lexer.ASSIGN) {
 		return nil
@@ -227,6 +320,133 @@ func (p *Parser) parseLetStatement() *LetStatement {
 	return stmt
 }
 
+// parseImportStatement analiza 'importar "ruta" [como nombre]' y
+// 'desde "ruta" importar a, b'
+func (p *Parser) parseImportStatement() *ImportStatement {
+	stmt := &ImportStatement{Token: p.curToken}
+	from := p.curTokenIs(lexer.FROM)
+
+	if !p.expectPeek(lexer.STRING) {
+		return nil
+	}
+	stmt.Path = p.curToken.Literal
+
+	if from {
+		if !p.expectPeek(lexer.IMPORT) {
+			return nil
+		}
+		for {
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			stmt.Names = append(stmt.Names, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if !p.peekTokenIs(lexer.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	} else if p.peekTokenIs(lexer.AS) {
+		p.nextToken()
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+		stmt.Alias = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	} else {
+		// sin 'como', el módulo toma el nombre del archivo sin extensión
+		name := moduleName(stmt.Path)
+		if !isIdentifierName(name) {
+			msg := fmt.Sprintf("línea %d, columna %d: el módulo %q necesita un nombre: usa 'como'",
+				stmt.Token.Line, stmt.Token.Column, stmt.Path)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		stmt.Alias = &Identifier{Token: stmt.Token, Value: name}
+	}
+
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// moduleName devuelve el nombre base de una ruta sin su extensión
+func moduleName(path string) string {
+	name := path[strings.LastIndex(path, "/")+1:]
+	if dot := strings.LastIndex(name, "."); dot > 0 {
+		name = name[:dot]
+	}
+	return name
+}
+
+// isIdentifierName indica si name se lee como un identificador y no como
+// palabra reservada
+func isIdentifierName(name string) bool {
+	if name == "" || lexer.LookupIdent(name) != lexer.IDENT {
+		return false
+	}
+	for i, ch := range name {
+		letter := ch == '_' || unicode.IsLetter(ch)
+		if !letter && (i == 0 || !unicode.IsDigit(ch)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseExportStatement analiza 'exportar' seguido de una declaración con
+// nombre: guarda, fun, clase, rasgo o enum
+func (p *Parser) parseExportStatement() *ExportStatement {
+	stmt := &ExportStatement{Token: p.curToken}
+	p.nextToken()
+
+	stmt.Statement = p.parseStatement()
+	switch s := stmt.Statement.(type) {
+	case *LetStatement:
+		if s == nil {
+			return nil
+		}
+		if s.Pattern != nil {
+			for _, name := range s.Pattern.Names {
+				stmt.Names = append(stmt.Names, name.Value)
+			}
+			if s.Pattern.Rest != nil {
+				stmt.Names = append(stmt.Names, s.Pattern.Rest.Value)
+			}
+		} else if s.Name != nil {
+			stmt.Names = append(stmt.Names, s.Name.Value)
+		}
+	case *ExpressionStatement:
+		switch e := s.Expression.(type) {
+		case *FunctionLiteral:
+			if e.Name != "" {
+				stmt.Names = append(stmt.Names, e.Name)
+			}
+		case *ClassLiteral:
+			if e.Name != nil {
+				stmt.Names = append(stmt.Names, e.Name.Value)
+			}
+		case *TraitLiteral:
+			if e.Name != nil {
+				stmt.Names = append(stmt.Names, e.Name.Value)
+			}
+		case *EnumLiteral:
+			if e.Name != nil {
+				stmt.Names = append(stmt.Names, e.Name.Value)
+			}
+		}
+	}
+
+	if len(stmt.Names) == 0 {
+		msg := fmt.Sprintf("línea %d, columna %d: 'exportar' espera una declaración con nombre",
+			stmt.Token.Line, stmt.Token.Column)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseReturnStatement() *ReturnStatement {
 	stmt := &ReturnStatement{Token: p.curToken}
 
@@ -288,7 +508,7 @@ func (p *Parser) parseNumberLiteral() Expression {
 	lit := &IntegerLiteral{Token: p.curToken}
 
 	// Verificar si es un número decimal
-	if strconv.ContainsAny(p.curToken.Literal, ".") {
+	if strings.ContainsAny(p.curToken.Literal, ".") {
 		value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 		if err != nil {
 			msg := fmt.Sprintf("línea %d, columna %d: no se pudo analizar %q como número decimal",
@@ -316,6 +536,10 @@ func (p *Parser) parseStringLiteral() Expression {
 	return &StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseDecimalLiteral() Expression {
+	return &DecimalLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseBooleanLiteral() Expression {
 	return &BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(lexer.TRUE)}
 }
@@ -351,32 +575,119 @@ func (p *Parser) parseInfixExpression(left Expression) Expression {
 	return expression
 }
 
-func (p *Parser) parseGroupedExpression() Expression {
+func (p *Parser) parseAssignExpression(target Expression) Expression {
+	expression := &AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	if !isAssignTarget(target, expression.Operator) {
+		msg := fmt.Sprintf("línea %d, columna %d: no se puede asignar a %s",
+			p.curToken.Line, p.curToken.Column, target.String())
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	// La asignación es asociativa por la derecha: a = b = 1
 	p.nextToken()
+	expression.Value = p.parseExpression(LOWEST)
 
-	exp := p.parseExpression(LOWEST)
+	return expression
+}
 
-	if !p.expectPeek(lexer.RPAREN) {
+// isAssignTarget indica si una expresión puede recibir una asignación. ':='
+// solo declara variables, y no se puede asignar a través de ?. ni ?[
+func isAssignTarget(target Expression, operator string) bool {
+	switch target := target.(type) {
+	case *Identifier:
+		return true
+	case *DotExpression:
+		return operator == "=" && !target.Optional
+	case *IndexExpression:
+		return operator == "=" && !target.Optional
+	default:
+		return false
+	}
+}
+
+func (p *Parser) parseYieldExpression() Expression {
+	exp := &YieldExpression{Token: p.curToken}
+
+	if len(p.functions) == 0 {
+		msg := fmt.Sprintf("línea %d, columna %d: producir solo puede usarse dentro de una función",
+			p.curToken.Line, p.curToken.Column)
+		p.errors = append(p.errors, msg)
 		return nil
 	}
+	p.functions[len(p.functions)-1].IsGenerator = true
+
+	// 'producir' solo, al final de la línea o del bloque, no produce valor
+	if p.peekTokenIs(lexer.RBRACE) || p.peekTokenIs(lexer.SEMICOLON) || p.peekTokenIs(lexer.EOF) ||
+		p.peekToken.Line != p.curToken.Line {
+		return exp
+	}
+
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
 
 	return exp
 }
 
-func (p *Parser) parseIfExpression() Expression {
-	expression := &IfExpression{Token: p.curToken}
+func (p *Parser) parseSpreadExpression() Expression {
+	exp := &SpreadExpression{Token: p.curToken}
 
-	if !p.expectPeek(lexer.LPAREN) {
-		return nil
+	p.nextToken()
+	exp.Value = p.parseExpression(PREFIX)
+
+	return exp
+}
+
+// parseGroupedExpression analiza una expresión entre paréntesis o una tupla:
+// () es la tupla vacía, (a,) una tupla de un elemento y (a, b) una de dos
+func (p *Parser) parseGroupedExpression() Expression {
+	tok := p.curToken
+
+	if p.peekTokenIs(lexer.RPAREN) {
+		p.nextToken()
+		return &TupleLiteral{Token: tok, Elements: []Expression{}}
 	}
 
 	p.nextToken()
-	expression.Condition = p.parseExpression(LOWEST)
+
+	exp := p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(lexer.COMMA) {
+		tuple := &TupleLiteral{Token: tok, Elements: []Expression{exp}}
+		for p.peekTokenIs(lexer.COMMA) {
+			p.nextToken()
+			if p.peekTokenIs(lexer.RPAREN) {
+				break
+			}
+			p.nextToken()
+			tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+		}
+		exp = tuple
+	}
 
 	if !p.expectPeek(lexer.RPAREN) {
 		return nil
 	}
 
+	return exp
+}
+
+func (p *Parser) parseIfExpression() Expression {
+	expression := &IfExpression{Token: p.curToken}
+
+	// Los paréntesis de la condición son opcionales: si (x > 1) { o si x > 1 {
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
 	if !p.expectPeek(lexer.LBRACE) {
 		return nil
 	}
@@ -386,6 +697,21 @@ func (p *Parser) parseIfExpression() Expression {
 	if p.peekTokenIs(lexer.ELSE) {
 		p.nextToken()
 
+		// sino si ... encadena otra condición como único contenido del bloque
+		if p.peekTokenIs(lexer.IF) {
+			p.nextToken()
+			token := p.curToken
+			nested := p.parseIfExpression()
+			if nested == nil {
+				return nil
+			}
+			expression.Alternative = &BlockStatement{
+				Token:      token,
+				Statements: []Statement{&ExpressionStatement{Token: token, Expression: nested}},
+			}
+			return expression
+		}
+
 		if !p.expectPeek(lexer.LBRACE) {
 			return nil
 		}
@@ -396,6 +722,24 @@ func (p *Parser) parseIfExpression() Expression {
 	return expression
 }
 
+// parseConditionalExpression analiza la forma en línea: valor si condición sino otro
+func (p *Parser) parseConditionalExpression(consequence Expression) Expression {
+	expression := &ConditionalExpression{Token: p.curToken, Consequence: consequence}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(TERNARY)
+
+	if !p.expectPeek(lexer.ELSE) {
+		return nil
+	}
+
+	// Asociativa por la derecha: a si x sino b si y sino c
+	p.nextToken()
+	expression.Alternative = p.parseExpression(TERNARY - 1)
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *BlockStatement {
 	block := &BlockStatement{Token: p.curToken}
 	block.Statements = []Statement{}
@@ -432,7 +776,9 @@ func (p *Parser) parseFunctionLiteral() Expression {
 		return nil
 	}
 
+	p.functions = append(p.functions, lit)
 	lit.Body = p.parseBlockStatement()
+	p.functions = p.functions[:len(p.functions)-1]
 
 	return lit
 }
@@ -495,17 +841,165 @@ func (p *Parser) parseExpressionList(end lexer.TokenType) []Expression {
 }
 
 func (p *Parser) parseArrayLiteral() Expression {
-	array := &ArrayLiteral{Token: p.curToken}
-	array.Elements = p.parseExpressionList(lexer.RBRACKET)
+	array := &ArrayLiteral{Token: p.curToken, Elements: []Expression{}}
+
+	if p.peekTokenIs(lexer.RBRACKET) {
+		p.nextToken()
+		return array
+	}
+
+	p.nextToken()
+	first := p.parseExpression(LOWEST)
+
+	// [x * 2 para x en lista]
+	if p.peekTokenIs(lexer.FOR) {
+		return p.parseComprehension(array.Token, nil, first, lexer.RBRACKET)
+	}
+
+	array.Elements = append(array.Elements, first)
+	for p.peekTokenIs(lexer.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		array.Elements = append(array.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(lexer.RBRACKET) {
+		return nil
+	}
+
 	return array
 }
 
+// parseComprehension analiza las cláusulas de una comprensión cuyo valor (y
+// clave, en los mapas) ya se leyó; el siguiente token es el primer 'para'
+func (p *Parser) parseComprehension(tok lexer.Token, key, value Expression, end lexer.TokenType) Expression {
+	exp := &ComprehensionExpression{Token: tok, Key: key, Value: value}
+
+	for p.peekTokenIs(lexer.FOR) {
+		p.nextToken()
+		clause := &ComprehensionClause{Token: p.curToken}
+
+		p.nextToken()
+		clause.Target = p.parseComprehensionTarget()
+		if clause.Target == nil {
+			return nil
+		}
+
+		if !p.expectPeek(lexer.IN) {
+			return nil
+		}
+
+		// El iterable y los filtros no pueden consumir el 'si' siguiente
+		// como si fuera una expresión condicional
+		p.nextToken()
+		clause.Iterable = p.parseExpression(TERNARY)
+
+		for p.peekTokenIs(lexer.IF) {
+			p.nextToken()
+			p.nextToken()
+			clause.Conditions = append(clause.Conditions, p.parseExpression(TERNARY))
+		}
+
+		exp.Clauses = append(exp.Clauses, clause)
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseComprehensionTarget analiza x, (a, b) o a, b, ...resto antes del 'en'
+// de una comprensión; el token actual es el primero del objetivo
+func (p *Parser) parseComprehensionTarget() Expression {
+	if p.curTokenIs(lexer.LPAREN) {
+		return p.parseDestructuringPattern()
+	}
+
+	if !p.curTokenIs(lexer.IDENT) {
+		msg := fmt.Sprintf("línea %d, columna %d: se esperaba un nombre después de 'para', se obtuvo %s",
+			p.curToken.Line, p.curToken.Column, p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	first := &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.peekTokenIs(lexer.COMMA) {
+		return first
+	}
+
+	pattern := &DestructuringPattern{Token: p.curToken, Names: []*Identifier{first}}
+	for p.peekTokenIs(lexer.COMMA) {
+		p.nextToken()
+		p.nextToken()
+
+		if p.curTokenIs(lexer.ELLIPSIS) {
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			pattern.Rest = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			return pattern
+		}
+
+		if !p.curTokenIs(lexer.IDENT) {
+			msg := fmt.Sprintf("línea %d, columna %d: se esperaba un nombre en la desestructuración, se obtuvo %s",
+				p.curToken.Line, p.curToken.Column, p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		pattern.Names = append(pattern.Names, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	return pattern
+}
+
 func (p *Parser) parseIndexExpression(left Expression) Expression {
-	exp := &IndexExpression{Token: p.curToken, Left: left}
+	exp := &IndexExpression{Token: p.curToken, Left: left, Optional: p.curTokenIs(lexer.QUESTION_BRACKET)}
+
+	// lista[:fin] empieza directamente con ':'
+	if p.peekTokenIs(lexer.COLON) {
+		return p.parseSliceExpression(exp, nil)
+	}
 
 	p.nextToken()
 	exp.Index = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(lexer.COLON) {
+		return p.parseSliceExpression(exp, exp.Index)
+	}
+
+	if !p.expectPeek(lexer.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseSliceExpression analiza el resto de lista[inicio:fin:paso]; el token
+// actual es el último antes del primer ':'
+func (p *Parser) parseSliceExpression(index *IndexExpression, start Expression) Expression {
+	exp := &SliceExpression{
+		Token:    index.Token,
+		Left:     index.Left,
+		Start:    start,
+		Optional: index.Optional,
+	}
+
+	p.nextToken() // primer ':'
+	if !p.peekTokenIs(lexer.COLON) && !p.peekTokenIs(lexer.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(lexer.COLON) {
+		p.nextToken() // segundo ':'
+		if !p.peekTokenIs(lexer.RBRACKET) {
+			p.nextToken()
+			exp.Step = p.parseExpression(LOWEST)
+		}
+	}
+
 	if !p.expectPeek(lexer.RBRACKET) {
 		return nil
 	}
@@ -513,6 +1007,8 @@ func (p *Parser) parseIndexExpression(left Expression) Expression {
 	return exp
 }
 
+// parseHashLiteral analiza un mapa {clave: valor} o un conjunto {a, b}; se
+// distinguen por el ':' tras el primer elemento. {} es siempre un mapa vacío.
 func (p *Parser) parseHashLiteral() Expression {
 	hash := &HashLiteral{Token: p.curToken}
 	hash.Pairs = make(map[Expression]Expression)
@@ -525,14 +1021,26 @@ func (p *Parser) parseHashLiteral() Expression {
 	p.nextToken()
 	key := p.parseExpression(LOWEST)
 
-	if !p.expectPeek(lexer.COLON) {
-		return nil
+	// {x para x en lista}
+	if p.peekTokenIs(lexer.FOR) {
+		return p.parseComprehension(hash.Token, nil, key, lexer.RBRACE)
 	}
 
+	if !p.peekTokenIs(lexer.COLON) {
+		return p.parseSetLiteral(hash.Token, key)
+	}
+	p.nextToken()
+
 	p.nextToken()
 	value := p.parseExpression(LOWEST)
 
+	// {k: v para k, v en mapa}
+	if p.peekTokenIs(lexer.FOR) {
+		return p.parseComprehension(hash.Token, key, value, lexer.RBRACE)
+	}
+
 	hash.Pairs[key] = value
+	hash.Keys = append(hash.Keys, key)
 
 	for p.peekTokenIs(lexer.COMMA) {
 		p.nextToken()
@@ -547,6 +1055,7 @@ func (p *Parser) parseHashLiteral() Expression {
 		value := p.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 	}
 
 	if !p.expectPeek(lexer.RBRACE) {
@@ -556,8 +1065,25 @@ func (p *Parser) parseHashLiteral() Expression {
 	return hash
 }
 
+// parseSetLiteral analiza el resto de un conjunto cuyo primer elemento ya se leyó
+func (p *Parser) parseSetLiteral(tok lexer.Token, first Expression) Expression {
+	set := &SetLiteral{Token: tok, Elements: []Expression{first}}
+
+	for p.peekTokenIs(lexer.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		set.Elements = append(set.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(lexer.RBRACE) {
+		return nil
+	}
+
+	return set
+}
+
 func (p *Parser) parseDotExpression(object Expression) Expression {
-	exp := &DotExpression{Token: p.curToken, Object: object}
+	exp := &DotExpression{Token: p.curToken, Object: object, Optional: p.curTokenIs(lexer.QUESTION_DOT)}
 
 	if !p.expectPeek(lexer.IDENT) {
 		return nil
@@ -589,15 +1115,100 @@ func (p *Parser) parseWhileExpression() Expression {
 	return exp
 }
 
+// parseForInExpression analiza el resto de 'para objetivo en iterable { ... }'
+func (p *Parser) parseForInExpression(forToken lexer.Token, target Expression) Expression {
+	exp := &ForInExpression{Token: forToken, Target: target}
+
+	if !p.expectPeek(lexer.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	exp.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	exp.Body = p.parseBlockStatement()
+	return exp
+}
+
+// parseDestructuringPattern analiza (a, b, ...resto); el token actual es '('
+func (p *Parser) parseDestructuringPattern() *DestructuringPattern {
+	tok := p.curToken
+	p.nextToken()
+	return p.parseDestructuringPatternFrom(tok)
+}
+
+// parseDestructuringPatternFrom analiza un patrón cuyo primer nombre (o '...')
+// es el token actual
+func (p *Parser) parseDestructuringPatternFrom(tok lexer.Token) *DestructuringPattern {
+	pattern := &DestructuringPattern{Token: tok}
+
+	for {
+		if p.curTokenIs(lexer.ELLIPSIS) {
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			pattern.Rest = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			// ...resto debe ser el último nombre
+			if !p.expectPeek(lexer.RPAREN) {
+				return nil
+			}
+			return pattern
+		}
+
+		if !p.curTokenIs(lexer.IDENT) {
+			msg := fmt.Sprintf("línea %d, columna %d: se esperaba un nombre en la desestructuración, se obtuvo %s",
+				p.curToken.Line, p.curToken.Column, p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		pattern.Names = append(pattern.Names, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if p.peekTokenIs(lexer.RPAREN) {
+			p.nextToken()
+			return pattern
+		}
+		if !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseForExpression() Expression {
+	// para x en iterable { ... }
+	if p.peekTokenIs(lexer.IDENT) {
+		forToken := p.curToken
+		p.nextToken()
+		return p.parseForInExpression(forToken, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
 	exp := &ForExpression{Token: p.curToken}
 
 	if !p.expectPeek(lexer.LPAREN) {
 		return nil
 	}
 
+	// para (a, b) en iterable { ... }: un nombre seguido de ',' o ')' no
+	// puede empezar la inicialización de un bucle clásico
+	if p.peekTokenIs(lexer.IDENT) || p.peekTokenIs(lexer.ELLIPSIS) {
+		next := p.peekToken
+		p.nextToken()
+		if next.Type == lexer.ELLIPSIS || p.peekTokenIs(lexer.COMMA) || p.peekTokenIs(lexer.RPAREN) {
+			pattern := p.parseDestructuringPatternFrom(exp.Token)
+			if pattern == nil {
+				return nil
+			}
+			return p.parseForInExpression(exp.Token, pattern)
+		}
+	} else {
+		p.nextToken()
+	}
+
 	// Inicialización
-	p.nextToken()
 	if !p.curTokenIs(lexer.SEMICOLON) {
 		exp.Init = p.parseStatement()
 	}
@@ -656,6 +1267,27 @@ func (p *Parser) parseClassLiteral() Expression {
 		class.Parent = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
 
+	// Rasgos
+	if p.peekTokenIs(lexer.USES) {
+		p.nextToken()
+
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+
+		class.Traits = append(class.Traits, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		for p.peekTokenIs(lexer.COMMA) {
+			p.nextToken()
+
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+
+			class.Traits = append(class.Traits, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		}
+	}
+
 	// Implementación de interfaces
 	if p.peekTokenIs(lexer.IMPLEMENTS) {
 		p.nextToken()
@@ -685,24 +1317,270 @@ func (p *Parser) parseClassLiteral() Expression {
 	p.nextToken()
 	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
 		if p.curTokenIs(lexer.FUNCTION) {
-			method := p.parseFunctionLiteral().(*FunctionLiteral)
+			method, ok := p.parseFunctionLiteral().(*FunctionLiteral)
+			if !ok {
+				return nil
+			}
 			class.Methods = append(class.Methods, method)
+		} else if p.curTokenIs(lexer.IDENT) && p.curToken.Literal == "crear" && p.peekTokenIs(lexer.LPAREN) {
+			// El constructor puede declararse sin 'fun': crear(a, b) { ... }
+			constructor, ok := p.parseFunctionLiteral().(*FunctionLiteral)
+			if !ok {
+				return nil
+			}
+			constructor.Name = "crear"
+			class.Methods = append(class.Methods, constructor)
+		} else if p.curTokenIs(lexer.ABSTRACT) {
+			method := p.parseAbstractMethod()
+			if method == nil {
+				return nil
+			}
+			class.AbstractMethods = append(class.AbstractMethods, method)
+		} else if p.isAccessorStart() {
+			if !p.parseAccessor(class) {
+				return nil
+			}
 		} else if p.curTokenIs(lexer.VAR) {
 			property := p.parseLetStatement()
+			if property == nil {
+				return nil
+			}
 			class.Properties = append(class.Properties, property)
-		} else {
+		}
+		p.nextToken()
+	}
+
+	return class
+}
+
+// parseEnumLiteral analiza 'enum Nombre { A, B, C }'; los miembros pueden
+// separarse con comas o con saltos de línea
+func (p *Parser) parseEnumLiteral() Expression {
+	enum := &EnumLiteral{Token: p.curToken}
+
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+	enum.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for !p.peekTokenIs(lexer.RBRACE) {
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+		if seen[p.curToken.Literal] {
+			msg := fmt.Sprintf("línea %d, columna %d: el miembro %s está repetido en el enum %s",
+				p.curToken.Line, p.curToken.Column, p.curToken.Literal, enum.Name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[p.curToken.Literal] = true
+		enum.Members = append(enum.Members, &Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if p.peekTokenIs(lexer.COMMA) {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
+
+	if len(enum.Members) == 0 {
+		msg := fmt.Sprintf("línea %d, columna %d: el enum %s debe tener al menos un miembro",
+			enum.Token.Line, enum.Token.Column, enum.Name.Value)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return enum
+}
+
+// parseSwitchExpression analiza 'evaluar valor { cuando a, b { ... } defecto { ... } }'
+func (p *Parser) parseSwitchExpression() Expression {
+	exp := &SwitchExpression{Token: p.curToken}
+
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(lexer.RBRACE) {
+		switch {
+		case p.peekTokenIs(lexer.WHEN):
+			p.nextToken()
+			c := &SwitchCase{Token: p.curToken}
+
 			p.nextToken()
+			c.Values = append(c.Values, p.parseExpression(LOWEST))
+			for p.peekTokenIs(lexer.COMMA) {
+				p.nextToken()
+				p.nextToken()
+				c.Values = append(c.Values, p.parseExpression(LOWEST))
+			}
+
+			if !p.expectPeek(lexer.LBRACE) {
+				return nil
+			}
+			c.Body = p.parseBlockStatement()
+			exp.Cases = append(exp.Cases, c)
+		case p.peekTokenIs(lexer.DEFAULT) && exp.Default == nil:
+			p.nextToken()
+			if !p.expectPeek(lexer.LBRACE) {
+				return nil
+			}
+			exp.Default = p.parseBlockStatement()
+		default:
+			msg := fmt.Sprintf("línea %d, columna %d: se esperaba 'cuando' o 'defecto' en evaluar, se obtuvo %s",
+				p.peekToken.Line, p.peekToken.Column, p.peekToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
 		}
 	}
+	p.nextToken()
+
+	return exp
+}
+
+// parseTraitLiteral analiza 'rasgo Nombre { ... }', cuyo cuerpo tiene métodos
+// y métodos requeridos ('abstracta fun')
+func (p *Parser) parseTraitLiteral() Expression {
+	trait := &TraitLiteral{Token: p.curToken}
+
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+	trait.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		switch {
+		case p.curTokenIs(lexer.FUNCTION):
+			method, ok := p.parseFunctionLiteral().(*FunctionLiteral)
+			if !ok {
+				return nil
+			}
+			trait.Methods = append(trait.Methods, method)
+		case p.curTokenIs(lexer.ABSTRACT):
+			method := p.parseAbstractMethod()
+			if method == nil {
+				return nil
+			}
+			trait.Required = append(trait.Required, method)
+		default:
+			msg := fmt.Sprintf("línea %d, columna %d: un rasgo solo puede contener métodos, se obtuvo %s",
+				p.curToken.Line, p.curToken.Column, p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		p.nextToken()
+	}
+
+	return trait
+}
+
+// parseAbstractClass analiza 'abstracta clase Nombre { ... }'
+func (p *Parser) parseAbstractClass() Expression {
+	if !p.expectPeek(lexer.CLASS) {
+		return nil
+	}
+
+	class, ok := p.parseClassLiteral().(*ClassLiteral)
+	if !ok {
+		return nil
+	}
+	class.Abstract = true
 
 	return class
 }
 
+// parseAbstractMethod analiza 'abstracta fun nombre(params)', que no tiene
+// cuerpo; solo puede aparecer en una clase abstracta o en un rasgo
+func (p *Parser) parseAbstractMethod() *FunctionLiteral {
+	abstractToken := p.curToken
+
+	if !p.expectPeek(lexer.FUNCTION) {
+		return nil
+	}
+	method := &FunctionLiteral{Token: p.curToken}
+
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+	method.Name = p.curToken.Literal
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	method.Parameters = p.parseFunctionParameters()
+
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(lexer.LBRACE) {
+		msg := fmt.Sprintf("línea %d, columna %d: el método abstracto %s no puede tener cuerpo",
+			abstractToken.Line, abstractToken.Column, method.Name)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return method
+}
+
+// isAccessorStart indica si el token actual empieza una propiedad calculada.
+// 'obtener' y 'asignar' no son palabras reservadas: solo tienen este sentido
+// dentro de una clase y seguidas del nombre de la propiedad. Basta con eso
+// para tomarlos como propiedad; la cantidad de parámetros se comprueba
+// después y, si no es la correcta, es un error.
+func (p *Parser) isAccessorStart() bool {
+	return p.curTokenIs(lexer.IDENT) && p.peekTokenIs(lexer.IDENT) &&
+		(p.curToken.Literal == "obtener" || p.curToken.Literal == "asignar")
+}
+
+// parseAccessor analiza 'obtener nombre() { ... }' o 'asignar nombre(valor) { ... }'
+// y lo agrega a la clase. Un 'obtener' con parámetros o un 'asignar' sin
+// exactamente uno es un error de sintaxis: no vuelve a leerse como método.
+func (p *Parser) parseAccessor(class *ClassLiteral) bool {
+	kind := p.curToken
+	accessor, ok := p.parseFunctionLiteral().(*FunctionLiteral)
+	if !ok {
+		return false
+	}
+
+	expected := 0
+	if kind.Literal == "asignar" {
+		expected = 1
+	}
+	if len(accessor.Parameters) != expected {
+		msg := fmt.Sprintf("línea %d, columna %d: '%s %s' debe recibir %d parámetro(s), tiene %d",
+			kind.Line, kind.Column, kind.Literal, accessor.Name, expected, len(accessor.Parameters))
+		p.errors = append(p.errors, msg)
+		return false
+	}
+
+	if expected == 0 {
+		class.Getters = append(class.Getters, accessor)
+	} else {
+		class.Setters = append(class.Setters, accessor)
+	}
+	return true
+}
+
 func (p *Parser) parseNewExpression() Expression {
 	exp := &NewExpression{Token: p.curToken}
 
 	p.nextToken()
-	exp.Class = p.parseExpression(LOWEST)
+	// Con precedencia CALL los paréntesis quedan para los argumentos y no se
+	// interpretan como una llamada a la clase
+	exp.Class = p.parseExpression(CALL)
 
 	if p.peekTokenIs(lexer.LPAREN) {
 		p.nextToken()
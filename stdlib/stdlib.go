@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/umdis/gaby-interpreter/internal/evaluator"
 	"github.com/umdis/gaby-interpreter/internal/object"
 )
 
@@ -28,6 +32,16 @@ func LoadStdlib(env *object.Environment) {
 	registerBuiltin(env, "potencia", potencia)
 	registerBuiltin(env, "raiz", raiz)
 	
+	// Funciones de decimales exactos
+	registerBuiltin(env, "decimal", decimal)
+	registerBuiltin(env, "configurar_decimal", configurarDecimal)
+	registerBuiltin(env, "formato_decimal", formatoDecimal)
+	
+	// Funciones de fracciones
+	registerBuiltin(env, "fraccion", fraccion)
+	registerBuiltin(env, "numerador", numerador)
+	registerBuiltin(env, "denominador", denominador)
+	
 	// Funciones de texto
 	registerBuiltin(env, "texto", convertirATexto)
 	registerBuiltin(env, "num", convertirANumero)
@@ -37,6 +51,7 @@ func LoadStdlib(env *object.Environment) {
 	registerBuiltin(env, "contiene", contiene)
 	registerBuiltin(env, "reemplazar", reemplazar)
 	registerBuiltin(env, "dividir", dividir)
+	registerBuiltin(env, "comparar", comparar)
 	
 	// Funciones de tiempo
 	registerBuiltin(env, "ahora", ahora)
@@ -46,12 +61,29 @@ func LoadStdlib(env *object.Environment) {
 	registerBuiltin(env, "args", args)
 	registerBuiltin(env, "salir", salir)
 	registerBuiltin(env, "cargar", cargar)
+	registerBuiltin(env, "entorno", entorno)
+	registerBuiltin(env, "ejecutar_codigo", ejecutarCodigo)
 	
 	// Funciones de colecciones
 	registerBuiltin(env, "longitud", longitud)
 	registerBuiltin(env, "agregar", agregar)
 	registerBuiltin(env, "eliminar", eliminar)
 	registerBuiltin(env, "rango", rango)
+	registerBuiltin(env, "conjunto", conjunto)
+	registerBuiltin(env, "lista", lista)
+	registerBuiltin(env, "tupla", tupla)
+	registerBuiltin(env, "ordenar", ordenar)
+	registerBuiltin(env, "iterador", iterador)
+	
+	// Funciones de reflexión
+	registerBuiltin(env, "tipo", tipo)
+	registerBuiltin(env, "clase_de", claseDe)
+	registerBuiltin(env, "campos", campos)
+	registerBuiltin(env, "metodos", metodos)
+	registerBuiltin(env, "tiene_metodo", tieneMetodo)
+	registerBuiltin(env, "obtener", obtener)
+	registerBuiltin(env, "establecer", establecer)
+	registerBuiltin(env, "llamar", llamar)
 }
 
 // registerBuiltin registra una función incorporada en el entorno
@@ -120,12 +152,23 @@ func abs(args ...object.Object) object.Object {
 		return &object.Integer{Value: value}
 	case *object.Float:
 		return &object.Float{Value: math.Abs(arg.Value)}
+	case *object.Decimal:
+		return arg.Abs()
+	case *object.Rational:
+		return &object.Rational{Value: new(big.Rat).Abs(arg.Value)}
 	default:
 		return newError("argumento no válido para 'abs': %s", args[0].Type())
 	}
 }
 
 func redondear(args ...object.Object) object.Object {
+	// Los decimales exactos aceptan escala y modo de redondeo opcionales
+	if len(args) >= 1 && len(args) <= 3 {
+		if dec, ok := args[0].(*object.Decimal); ok {
+			return redondearDecimal(dec, args[1:]...)
+		}
+	}
+	
 	if len(args) != 1 {
 		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
 	}
@@ -250,6 +293,287 @@ func raiz(args ...object.Object) object.Object {
 	return &object.Float{Value: math.Sqrt(value)}
 }
 
+// Funciones de decimales exactos
+
+// decimal(valor, escala?, modo?) convierte un texto, entero o decimal a decimal exacto
+func decimal(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("número incorrecto de argumentos: se esperaba de 1 a 3, se obtuvo %d", len(args))
+	}
+	
+	var value *object.Decimal
+	
+	switch arg := args[0].(type) {
+	case *object.Decimal:
+		value = arg
+	case *object.Integer:
+		value = object.NewDecimalFromInt(arg.Value)
+	case *object.Float:
+		// Usar la representación más corta del float: 0.1 se convierte en 0.1 exacto
+		parsed, err := object.ParseDecimal(strconv.FormatFloat(arg.Value, 'f', -1, 64))
+		if err != nil {
+			return newError("no se pudo convertir %s a decimal exacto", arg.Inspect())
+		}
+		value = parsed
+	case *object.String:
+		parsed, err := object.ParseDecimal(arg.Value)
+		if err != nil {
+			return newError("%s", err)
+		}
+		value = parsed
+	case *object.Rational:
+		// Se redondea directamente desde la fracción para no redondear dos veces
+		if len(args) == 1 {
+			value := object.NewDecimalFromRat(arg.Value, object.DecimalDivisionScale, object.DecimalRounding)
+			return value.Normalize()
+		}
+		scale, err := decimalScale(args[1])
+		if err != nil {
+			return err
+		}
+		mode := object.DecimalRounding
+		if len(args) == 3 {
+			parsed, err := parseRoundingMode(args[2])
+			if err != nil {
+				return err
+			}
+			mode = parsed
+		}
+		return object.NewDecimalFromRat(arg.Value, scale, mode)
+	default:
+		return newError("argumento no válido para 'decimal': %s", args[0].Type())
+	}
+	
+	if len(args) == 1 {
+		return value
+	}
+	
+	return redondearDecimal(value, args[1:]...)
+}
+
+// maxDecimalScale limita los decimales de una escala; más allá los
+// coeficientes crecen sin medida y la escala dejaría de caber en un int32
+const maxDecimalScale = 1000
+
+// decimalScale valida una escala dada por el programa
+func decimalScale(arg object.Object) (int32, *object.Error) {
+	scale, ok := arg.(*object.Integer)
+	if !ok || scale.Value < 0 {
+		return 0, newError("la escala debe ser un entero no negativo, se obtuvo %s", arg.Inspect())
+	}
+	if scale.Value > maxDecimalScale {
+		return 0, newError("la escala no puede ser mayor que %d, se obtuvo %d", maxDecimalScale, scale.Value)
+	}
+	return int32(scale.Value), nil
+}
+
+// redondearDecimal redondea a la escala indicada (0 por defecto) con el modo
+// indicado o, si no se indica, con el modo configurado
+func redondearDecimal(value *object.Decimal, args ...object.Object) object.Object {
+	var scale int32
+	
+	if len(args) >= 1 {
+		parsed, err := decimalScale(args[0])
+		if err != nil {
+			return err
+		}
+		scale = parsed
+	}
+	
+	mode := object.DecimalRounding
+	if len(args) == 2 {
+		parsed, err := parseRoundingMode(args[1])
+		if err != nil {
+			return err
+		}
+		mode = parsed
+	}
+	
+	return value.Round(scale, mode)
+}
+
+// configurar_decimal(escala, modo?) fija los decimales de las divisiones y el modo de redondeo
+func configurarDecimal(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("número incorrecto de argumentos: se esperaba 1 o 2, se obtuvo %d", len(args))
+	}
+	
+	scale, err := decimalScale(args[0])
+	if err != nil {
+		return err
+	}
+	
+	if len(args) == 2 {
+		mode, err := parseRoundingMode(args[1])
+		if err != nil {
+			return err
+		}
+		object.DecimalRounding = mode
+	}
+	
+	object.DecimalDivisionScale = scale
+	return NULL
+}
+
+// formato_decimal(valor, decimales?, sep_miles?, sep_decimal?) da formato a un
+// decimal exacto, por ejemplo formato_decimal(d"1234.5", 2) = "1,234.50"
+func formatoDecimal(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 4 {
+		return newError("número incorrecto de argumentos: se esperaba de 1 a 4, se obtuvo %d", len(args))
+	}
+	
+	value, ok := args[0].(*object.Decimal)
+	if !ok {
+		converted := decimal(args[0])
+		if isError(converted) {
+			return converted
+		}
+		value = converted.(*object.Decimal)
+	}
+	
+	if len(args) >= 2 {
+		rounded := redondearDecimal(value, args[1])
+		if isError(rounded) {
+			return rounded
+		}
+		value = rounded.(*object.Decimal)
+	}
+	
+	thousands, point := ",", "."
+	if len(args) >= 3 {
+		sep, ok := args[2].(*object.String)
+		if !ok {
+			return newError("el separador de miles debe ser TEXTO, se obtuvo %s", args[2].Type())
+		}
+		thousands = sep.Value
+	}
+	if len(args) == 4 {
+		sep, ok := args[3].(*object.String)
+		if !ok {
+			return newError("el separador decimal debe ser TEXTO, se obtuvo %s", args[3].Type())
+		}
+		point = sep.Value
+	}
+	
+	text := value.Abs().String()
+	intPart, fracPart, hasPoint := strings.Cut(text, ".")
+	
+	var out strings.Builder
+	if value.Coef.Sign() < 0 {
+		out.WriteString("-")
+	}
+	for i, ch := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			out.WriteString(thousands)
+		}
+		out.WriteRune(ch)
+	}
+	if hasPoint {
+		out.WriteString(point)
+		out.WriteString(fracPart)
+	}
+	
+	return &object.String{Value: out.String()}
+}
+
+// parseRoundingMode traduce el nombre de un modo de redondeo ("mitad_par", "mitad_arriba")
+func parseRoundingMode(arg object.Object) (object.RoundingMode, *object.Error) {
+	name, ok := arg.(*object.String)
+	if !ok {
+		return 0, newError("el modo de redondeo debe ser TEXTO, se obtuvo %s", arg.Type())
+	}
+	
+	mode, ok := object.RoundingModeNames[name.Value]
+	if !ok {
+		return 0, newError("modo de redondeo desconocido: %s (use \"mitad_par\" o \"mitad_arriba\")", name.Value)
+	}
+	
+	return mode, nil
+}
+
+// Funciones de fracciones
+
+// fraccion(numerador, denominador?) crea una fracción exacta; también acepta
+// un texto como "1/3", un decimal exacto o un decimal de coma flotante
+func fraccion(args ...object.Object) object.Object {
+	if len(args) == 2 {
+		num, okNum := args[0].(*object.Integer)
+		den, okDen := args[1].(*object.Integer)
+		if !okNum || !okDen {
+			return newError("argumentos no válidos para 'fraccion': %s, %s", args[0].Type(), args[1].Type())
+		}
+		if den.Value == 0 {
+			return newError("el denominador de una fracción no puede ser cero")
+		}
+		return object.NewRational(num.Value, den.Value)
+	}
+	
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1 o 2, se obtuvo %d", len(args))
+	}
+	
+	switch arg := args[0].(type) {
+	case *object.Rational:
+		return arg
+	case *object.Integer:
+		return object.NewRationalFromInt(arg.Value)
+	case *object.Decimal:
+		return &object.Rational{Value: arg.Rat()}
+	case *object.Float:
+		value := new(big.Rat)
+		if value.SetFloat64(arg.Value) == nil {
+			return newError("no se pudo convertir %s a fracción", arg.Inspect())
+		}
+		return &object.Rational{Value: value}
+	case *object.String:
+		value, ok := new(big.Rat).SetString(strings.TrimSpace(arg.Value))
+		if !ok {
+			return newError("no se pudo convertir '%s' a fracción", arg.Value)
+		}
+		return &object.Rational{Value: value}
+	default:
+		return newError("argumento no válido para 'fraccion': %s", args[0].Type())
+	}
+}
+
+func numerador(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	switch arg := args[0].(type) {
+	case *object.Rational:
+		return bigIntToObject(arg.Value.Num())
+	case *object.Integer:
+		return arg
+	default:
+		return newError("argumento no válido para 'numerador': %s", args[0].Type())
+	}
+}
+
+func denominador(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	switch arg := args[0].(type) {
+	case *object.Rational:
+		return bigIntToObject(arg.Value.Denom())
+	case *object.Integer:
+		return &object.Integer{Value: 1}
+	default:
+		return newError("argumento no válido para 'denominador': %s", args[0].Type())
+	}
+}
+
+// bigIntToObject devuelve un ENTERO si el valor cabe en 64 bits, o una fracción entera si no
+func bigIntToObject(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.Rational{Value: new(big.Rat).SetInt(value)}
+}
+
 // Funciones de texto
 
 func convertirATexto(args ...object.Object) object.Object {
@@ -270,6 +594,10 @@ func convertirANumero(args ...object.Object) object.Object {
 		return arg
 	case *object.Float:
 		return arg
+	case *object.Decimal:
+		return arg
+	case *object.Rational:
+		return arg
 	case *object.String:
 		// Intentar convertir a entero
 		if intVal, err := strconv.ParseInt(arg.Value, 10, 64); err == nil {
@@ -375,6 +703,40 @@ func dividir(args ...object.Object) object.Object {
 	return newError("argumentos no válidos para 'dividir': %s, %s", args[0].Type(), args[1].Type())
 }
 
+// comparar devuelve -1, 0 o 1 según el orden de dos números o textos; con
+// intercalación "es" los textos se ordenan como en un diccionario español
+func comparar(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("número incorrecto de argumentos: se esperaba 2 o 3, se obtuvo %d", len(args))
+	}
+	
+	collation, err := collationArg(args, 2)
+	if err != nil {
+		return err
+	}
+	
+	result, err := evaluator.Compare(args[0], args[1], collation)
+	if err != nil {
+		return err
+	}
+	
+	return &object.Integer{Value: int64(result)}
+}
+
+// collationArg lee la intercalación opcional en la posición indicada
+func collationArg(args []object.Object, pos int) (string, *object.Error) {
+	if len(args) <= pos {
+		return evaluator.CollationBinary, nil
+	}
+	
+	name, ok := args[pos].(*object.String)
+	if !ok {
+		return "", newError("la intercalación debe ser TEXTO, se obtuvo %s", args[pos].Type())
+	}
+	
+	return name.Value, nil
+}
+
 // Funciones de tiempo
 
 func ahora(args ...object.Object) object.Object {
@@ -474,28 +836,62 @@ func longitud(args ...object.Object) object.Object {
 	
 	switch arg := args[0].(type) {
 	case *object.String:
-		return &object.Integer{Value: int64(len(arg.Value))}
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 	case *object.Array:
 		return &object.Integer{Value: int64(len(arg.Elements))}
 	case *object.Hash:
-		return &object.Integer{Value: int64(len(arg.Pairs))}
+		return &object.Integer{Value: int64(arg.Len())}
+	case *object.Set:
+		return &object.Integer{Value: int64(arg.Len())}
+	case *object.Tuple:
+		return &object.Integer{Value: int64(len(arg.Elements))}
+	case *object.Range:
+		return evaluator.RangeLength(arg)
+	case *object.Enum:
+		return &object.Integer{Value: int64(len(arg.Members))}
 	default:
 		return newError("argumento no válido para 'longitud': %s", args[0].Type())
 	}
 }
 
+// rangeAsList convierte un RANGO en la lista de sus elementos, que es lo que
+// rango() devolvía antes de existir RANGO; cualquier otro valor queda igual
+func rangeAsList(arg object.Object) (object.Object, *object.Error) {
+	if _, ok := arg.(*object.Range); !ok {
+		return arg, nil
+	}
+	elements, err := evaluator.Collect(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &object.Array{Elements: elements}, nil
+}
+
 func agregar(args ...object.Object) object.Object {
 	if len(args) != 2 {
 		return newError("número incorrecto de argumentos: se esperaba 2, se obtuvo %d", len(args))
 	}
 	
-	if arr, ok := args[0].(*object.Array); ok {
+	collection, err := rangeAsList(args[0])
+	if err != nil {
+		return err
+	}
+	
+	if arr, ok := collection.(*object.Array); ok {
 		newElements := make([]object.Object, len(arr.Elements))
 		copy(newElements, arr.Elements)
 		newElements = append(newElements, args[1])
 		return &object.Array{Elements: newElements}
 	}
 	
+	if set, ok := args[0].(*object.Set); ok {
+		result := set.Copy()
+		if err := evaluator.SetAdd(result, args[1]); err != nil {
+			return err
+		}
+		return result
+	}
+	
 	return newError("primer argumento no válido para 'agregar': %s", args[0].Type())
 }
 
@@ -504,7 +900,12 @@ func eliminar(args ...object.Object) object.Object {
 		return newError("número incorrecto de argumentos: se esperaba 2, se obtuvo %d", len(args))
 	}
 	
-	if arr, ok := args[0].(*object.Array); ok {
+	collection, err := rangeAsList(args[0])
+	if err != nil {
+		return err
+	}
+	
+	if arr, ok := collection.(*object.Array); ok {
 		if idx, ok := args[1].(*object.Integer); ok {
 			i := idx.Value
 			if i < 0 || i >= int64(len(arr.Elements)) {
@@ -519,15 +920,53 @@ func eliminar(args ...object.Object) object.Object {
 		}
 	}
 	
+	// Conjuntos y mapas se reconstruyen sin el elemento para no romper las
+	// cadenas de sondeo de las claves que colisionan
+	if set, ok := args[0].(*object.Set); ok {
+		result := object.NewSet()
+		for _, e := range set.Items() {
+			equal, err := evaluator.KeysEqual(e, args[1])
+			if err != nil {
+				return err
+			}
+			if !equal {
+				if err := evaluator.SetAdd(result, e); err != nil {
+					return err
+				}
+			}
+		}
+		return result
+	}
+	
+	if hash, ok := args[0].(*object.Hash); ok {
+		result := object.NewHash()
+		for _, pair := range hash.Items() {
+			equal, err := evaluator.KeysEqual(pair.Key, args[1])
+			if err != nil {
+				return err
+			}
+			if !equal {
+				if err := evaluator.HashPut(result, pair.Key, pair.Value); err != nil {
+					return err
+				}
+			}
+		}
+		return result
+	}
+	
 	return newError("argumentos no válidos para 'eliminar': %s, %s", args[0].Type(), args[1].Type())
 }
 
+// rango(inicio, fin, paso?) devuelve un RANGO de inicio a fin, ambos
+// incluidos. Los números no se generan hasta que se recorre; lista(rango)
+// lo convierte en una lista.
 func rango(args ...object.Object) object.Object {
-	if len(args) != 2 {
-		return newError("número incorrecto de argumentos: se esperaba 2, se obtuvo %d", len(args))
+	if len(args) != 2 && len(args) != 3 {
+		return newError("número incorrecto de argumentos: se esperaban 2 o 3, se obtuvo %d", len(args))
 	}
 	
 	var inicio, fin int64
+	paso := int64(1)
 	
 	switch arg := args[0].(type) {
 	case *object.Integer:
@@ -543,26 +982,459 @@ func rango(args ...object.Object) object.Object {
 		return newError("segundo argumento no válido para 'rango': %s", args[1].Type())
 	}
 	
-	if inicio > fin {
+	if len(args) == 3 {
+		arg, ok := args[2].(*object.Integer)
+		if !ok {
+			return newError("tercer argumento no válido para 'rango': %s", args[2].Type())
+		}
+		if arg.Value == 0 {
+			return newError("el paso de 'rango' no puede ser cero")
+		}
+		paso = arg.Value
+	} else if inicio > fin {
 		return newError("el inicio no puede ser mayor que el fin")
 	}
 	
-	elements := make([]object.Object, 0, fin-inicio+1)
-	for i := inicio; i <= fin; i++ {
-		elements = append(elements, &object.Integer{Value: i})
+	return &object.Range{Start: inicio, Stop: fin, Step: paso, Inclusive: true}
+}
+
+// conjunto crea un conjunto vacío o con los elementos de cualquier iterable
+func conjunto(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("número incorrecto de argumentos: se esperaba 0 o 1, se obtuvo %d", len(args))
+	}
+	
+	if len(args) == 0 {
+		return object.NewSet()
+	}
+	
+	elements, err := evaluator.Collect(args[0])
+	if err != nil {
+		return err
+	}
+	
+	return evaluator.NewSet(elements)
+}
+
+// ordenar devuelve una lista nueva con los números o textos de un iterable
+// ordenados de menor a mayor; la intercalación opcional se aplica a los textos
+func ordenar(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("número incorrecto de argumentos: se esperaba 1 o 2, se obtuvo %d", len(args))
+	}
+	
+	collation, err := collationArg(args, 1)
+	if err != nil {
+		return err
+	}
+	
+	elements, err := evaluator.Collect(args[0])
+	if err != nil {
+		return err
+	}
+	
+	var sortErr *object.Error
+	sort.SliceStable(elements, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		result, err := evaluator.Compare(elements[i], elements[j], collation)
+		if err != nil {
+			sortErr = err
+		}
+		return result < 0
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+	
+	return &object.Array{Elements: elements}
+}
+
+// tupla devuelve una tupla con los elementos de cualquier iterable
+func tupla(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	elements := lista(args...)
+	if isError(elements) {
+		return elements
+	}
+	
+	return &object.Tuple{Elements: elements.(*object.Array).Elements}
+}
+
+// iterador devuelve un iterador sobre cualquier iterable, con los métodos
+// siguiente() y tiene_siguiente()
+func iterador(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	it, err := evaluator.IteratorOf(args[0])
+	if err != nil {
+		return err
+	}
+	
+	return it
+}
+
+// lista devuelve una lista nueva con los elementos de cualquier iterable
+func lista(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	elements, err := evaluator.Collect(args[0])
+	if err != nil {
+		return err
+	}
+	
+	return &object.Array{Elements: elements}
+}
+
+// sandboxExcluded son las funciones que actúan sobre el proceso y no se
+// ofrecen al código aislado: salir terminaría el programa anfitrión y cargar
+// ejecutaría archivos fuera del entorno
+var sandboxExcluded = []string{"salir", "cargar"}
+
+// sandbox crea un entorno vacío para código aislado; la biblioteca estándar
+// queda en un entorno exterior propio, así lo que el código defina o
+// reasigne no afecta a nadie más. Aísla variables, no recursos: el código
+// aún puede leer la entrada o importar módulos.
+func sandbox() *object.Environment {
+	base := object.NewEnvironment()
+	LoadStdlib(base)
+	for _, name := range sandboxExcluded {
+		base.Delete(name)
+	}
+	return object.NewEnclosedEnvironment(base)
+}
+
+// entorno crea un ENTORNO para ejecutar_codigo: vacío, con las variables de un
+// mapa {"nombre": valor}, o con una copia de las variables de otro entorno;
+// lo que el código asigne queda en la copia y no cambia el original
+func entorno(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("número incorrecto de argumentos: se esperaba 0 o 1, se obtuvo %d", len(args))
+	}
+	
+	if len(args) == 0 {
+		return &object.EnvironmentValue{Env: sandbox()}
+	}
+	
+	switch arg := args[0].(type) {
+	case *object.EnvironmentValue:
+		env := sandbox()
+		for name, value := range arg.Env.Bindings(arg.Env.Root()) {
+			env.Set(name, value)
+		}
+		return &object.EnvironmentValue{Env: env}
+	case *object.Hash:
+		env := sandbox()
+		for _, pair := range arg.Items() {
+			name, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("los nombres de un entorno deben ser TEXTO, se obtuvo %s", pair.Key.Type())
+			}
+			env.Set(name.Value, pair.Value)
+		}
+		return &object.EnvironmentValue{Env: env}
+	default:
+		return newError("argumento no válido para 'entorno': %s", args[0].Type())
+	}
+}
+
+// ejecutar_codigo analiza y evalúa código fuente. Sin entorno se ejecuta en
+// uno nuevo y aislado; con un ENTORNO, las definiciones del código quedan en
+// él. Los errores del código no detienen al programa que lo ejecuta: devuelve
+// un MAPA {"valor": último valor, "errores": [{"linea", "columna", "mensaje"}]}
+// con la lista de errores vacía si todo fue bien. Los errores de ejecución no
+// tienen posición y llevan nulo en "linea" y "columna".
+func ejecutarCodigo(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("número incorrecto de argumentos: se esperaba 1 o 2, se obtuvo %d", len(args))
+	}
+	
+	source, ok := args[0].(*object.String)
+	if !ok {
+		return newError("primer argumento no válido para 'ejecutar_codigo': %s", args[0].Type())
+	}
+	
+	env := sandbox()
+	if len(args) == 2 {
+		environment, ok := args[1].(*object.EnvironmentValue)
+		if !ok {
+			return newError("segundo argumento no válido para 'ejecutar_codigo': se esperaba ENTORNO, se obtuvo %s", args[1].Type())
+		}
+		env = environment.Env
+	}
+	
+	result, syntaxErrors := evaluator.EvalSource(source.Value, env)
+	errors := []object.Object{}
+	for _, message := range syntaxErrors {
+		errors = append(errors, syntaxError(message))
+	}
+	if err, ok := result.(*object.Error); ok {
+		errors = append(errors, codeError(NULL, NULL, err.Message))
+		result = nil
+	}
+	if result == nil {
+		result = NULL
+	}
+	
+	return stringMap([]string{"valor", "errores"}, result, &object.Array{Elements: errors})
+}
+
+// syntaxError convierte un error del analizador, que empieza con
+// "línea L, columna C: ", en su MAPA de error con la posición separada
+func syntaxError(message string) object.Object {
+	var line, column int64
+	if _, err := fmt.Sscanf(message, "línea %d, columna %d:", &line, &column); err == nil {
+		if _, rest, ok := strings.Cut(message, ": "); ok {
+			return codeError(&object.Integer{Value: line}, &object.Integer{Value: column}, rest)
+		}
+	}
+	return codeError(NULL, NULL, message)
+}
+
+// codeError crea el MAPA que describe un error de ejecutar_codigo
+func codeError(line, column object.Object, message string) object.Object {
+	return stringMap([]string{"linea", "columna", "mensaje"}, line, column, &object.String{Value: message})
+}
+
+// stringMap crea un MAPA que asocia cada nombre con el valor en su posición
+func stringMap(names []string, values ...object.Object) *object.Hash {
+	hash := object.NewHash()
+	for i, name := range names {
+		// Las claves de texto siempre se pueden usar, así que no hay error
+		evaluator.HashPut(hash, &object.String{Value: name}, values[i])
+	}
+	return hash
+}
+
+// Reflexión. Los nombres que empiezan con '_' son privados: no se listan
+// ni se pueden leer, asignar o llamar desde estas funciones.
+
+// tipo devuelve el nombre del tipo de un valor, como "ENTERO" o "INSTANCIA"
+func tipo(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	return &object.String{Value: string(args[0].Type())}
+}
+
+// clase_de devuelve la clase de una instancia o el enum de uno de sus valores
+func claseDe(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	switch arg := args[0].(type) {
+	case *object.Instance:
+		return arg.Class
+	case *object.EnumMember:
+		return arg.Enum
+	default:
+		return newError("argumento no válido para 'clase_de': %s", args[0].Type())
+	}
+}
+
+// campos devuelve los nombres de los campos públicos de una instancia en orden alfabético
+func campos(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	// Las variables propias de un entorno también se listan como campos
+	if environment, ok := args[0].(*object.EnvironmentValue); ok {
+		return stringList(environment.Env.Names())
+	}
+	if module, ok := args[0].(*object.Module); ok {
+		return stringList(module.Exports)
+	}
+	
+	instance, ok := args[0].(*object.Instance)
+	if !ok {
+		return newError("argumento no válido para 'campos': %s", args[0].Type())
+	}
+	
+	names := []string{}
+	for name := range instance.Properties {
+		if !isPrivate(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	
+	return stringList(names)
+}
+
+// metodos devuelve los nombres de los métodos públicos de una clase o de la
+// clase de una instancia, incluidos los heredados
+func metodos(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("número incorrecto de argumentos: se esperaba 1, se obtuvo %d", len(args))
+	}
+	
+	class, err := classArg(args[0], "metodos")
+	if err != nil {
+		return err
+	}
+	
+	names := []string{}
+	for _, name := range evaluator.MethodNames(class) {
+		if !isPrivate(name) {
+			names = append(names, name)
+		}
+	}
+	
+	return stringList(names)
+}
+
+// tiene_metodo indica si una clase o instancia tiene un método público con ese nombre
+func tieneMetodo(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("número incorrecto de argumentos: se esperaba 2, se obtuvo %d", len(args))
+	}
+	
+	class, err := classArg(args[0], "tiene_metodo")
+	if err != nil {
+		return err
+	}
+	name, ok := args[1].(*object.String)
+	if !ok {
+		return newError("segundo argumento no válido para 'tiene_metodo': %s", args[1].Type())
+	}
+	
+	if isPrivate(name.Value) {
+		return FALSE
+	}
+	for _, method := range evaluator.MethodNames(class) {
+		if method == name.Value {
+			return TRUE
+		}
+	}
+	return FALSE
+}
+
+// obtener lee un campo o propiedad pública por su nombre: obtener(obj, "x") es obj.x
+func obtener(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("número incorrecto de argumentos: se esperaba 2, se obtuvo %d", len(args))
+	}
+	
+	name, err := memberName(args[1], "obtener")
+	if err != nil {
+		return err
+	}
+	
+	return evaluator.GetProperty(args[0], name)
+}
+
+// establecer asigna un campo o propiedad pública por su nombre:
+// establecer(obj, "x", v) es obj.x = v
+func establecer(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("número incorrecto de argumentos: se esperaba 3, se obtuvo %d", len(args))
+	}
+	
+	name, err := memberName(args[1], "establecer")
+	if err != nil {
+		return err
+	}
+	
+	return evaluator.SetProperty(args[0], name, args[2])
+}
+
+// llamar invoca un método público por su nombre con los argumentos de una
+// lista o tupla opcional: llamar(obj, "m", [a, b]) es obj.m(a, b)
+func llamar(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("número incorrecto de argumentos: se esperaba 2 o 3, se obtuvo %d", len(args))
+	}
+	
+	name, err := memberName(args[1], "llamar")
+	if err != nil {
+		return err
 	}
 	
+	callArgs := []object.Object{}
+	if len(args) == 3 {
+		switch arg := args[2].(type) {
+		case *object.Array:
+			callArgs = arg.Elements
+		case *object.Tuple:
+			callArgs = arg.Elements
+		default:
+			return newError("los argumentos de 'llamar' deben ser una LISTA o TUPLA, se obtuvo %s", args[2].Type())
+		}
+	}
+	
+	method := evaluator.GetProperty(args[0], name)
+	if isError(method) {
+		return method
+	}
+	switch method.(type) {
+	case *object.Function, *object.Builtin:
+		return evaluator.ApplyFunction(method, callArgs)
+	default:
+		return newError("%s no es un método de %s", name, args[0].Inspect())
+	}
+}
+
+// isPrivate indica si un nombre de campo o método es privado
+func isPrivate(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// memberName valida el nombre de campo o método que recibe una función de reflexión
+func memberName(arg object.Object, fnName string) (string, *object.Error) {
+	name, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("el nombre para '%s' debe ser TEXTO, se obtuvo %s", fnName, arg.Type())
+	}
+	if isPrivate(name.Value) {
+		return "", newError("'%s' no puede acceder a %s: es privado", fnName, name.Value)
+	}
+	return name.Value, nil
+}
+
+// classArg acepta una clase o una instancia, de la que toma su clase
+func classArg(arg object.Object, fnName string) (*object.Class, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Class:
+		return arg, nil
+	case *object.Instance:
+		return arg.Class, nil
+	default:
+		return nil, newError("argumento no válido para '%s': se esperaba CLASE o INSTANCIA, se obtuvo %s", fnName, arg.Type())
+	}
+}
+
+func stringList(values []string) *object.Array {
+	elements := make([]object.Object, 0, len(values))
+	for _, value := range values {
+		elements = append(elements, &object.String{Value: value})
+	}
 	return &object.Array{Elements: elements}
 }
 
 // Constantes y utilidades
 
+// Los mismos objetos que usa el evaluador: las condiciones comparan por identidad
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.Null{}
+	TRUE  = evaluator.TRUE
+	FALSE = evaluator.FALSE
+	NULL  = evaluator.NULL
 )
 
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
 func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
\ No newline at end of file